@@ -0,0 +1,455 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/helpers"
+	"github.com/mbrostami/chord/net"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultCacheTTL is the expiration of cached owners
+const defaultCacheTTL time.Duration = 30 * time.Second
+
+// defaultCallTimeout is the deadline of each rpc, so a hung node doesn't use the whole timeout of the operation
+const defaultCallTimeout time.Duration = 2 * time.Second
+
+// scanPage is the number of records requested in each page of scan
+const scanPage int = 100
+
+// ringMaxHops limits walking the ring, so a broken ring doesn't loop forever
+const ringMaxHops int = 1024
+
+var (
+	// ErrNotFound is returned when the key has no value or it's deleted
+	ErrNotFound = errors.New("key is not found")
+	// ErrTimeout is returned when the operation doesn't finish before its deadline
+	ErrTimeout = errors.New("operation timed out")
+	// ErrNotEnoughReplicas is returned when less replicas than the consistency level acknowledged the operation
+	ErrNotEnoughReplicas = errors.New("not enough replicas acknowledged")
+	// ErrNoSeed is returned when none of the seeds is reachable
+	ErrNoSeed = errors.New("no seed is reachable")
+)
+
+// Config of the client
+type Config struct {
+	// Seeds are node addresses (ip:port or dns-name:port), tried in order
+	Seeds []string
+	// Namespace of the keys
+	Namespace string
+	// Replicas must be the same as replicas of the ring
+	Replicas int
+	// CacheTTL is the expiration of cached owners
+	CacheTTL time.Duration
+	// CallTimeout is the deadline of each rpc in the attempts of an operation
+	CallTimeout time.Duration
+	// Options are the defaults of operations
+	Options
+	TLS      *net.TLSConfig     // nil means insecure
+	Token    string             // bearer token to authenticate to nodes
+	Identity ed25519.PrivateKey // signs written records if it's set
+}
+
+func (c Config) withDefaults() Config {
+	if c.Replicas == 0 {
+		c.Replicas = chord.REPLICAS
+	}
+	if c.CacheTTL == 0 {
+		c.CacheTTL = defaultCacheTTL
+	}
+	if c.CallTimeout == 0 {
+		c.CallTimeout = defaultCallTimeout
+	}
+	c.Options = c.Options.withDefaults()
+	return c
+}
+
+// Client reads and writes keys of a namespace in the ring
+// owners are found through the seeds and cached until they reject a request
+type Client struct {
+	config Config
+	sender *net.RemoteNodeSenderGrpc
+	seeds  []*chord.Node
+	owners *chord.LocationCache
+}
+
+// New makes a client of the ring, seeds are resolved but not contacted
+func New(config Config) (*Client, error) {
+	config = config.withDefaults()
+	if len(config.Seeds) == 0 {
+		return nil, errors.New("client: at least one seed is required")
+	}
+	seeds, err := net.ResolveSeeds(strings.Join(config.Seeds, ","))
+	if err != nil {
+		return nil, err
+	}
+	sender, err := net.NewRemoteNodeSenderGrpc(net.SenderConfig{
+		TLS:         config.TLS,
+		Token:       config.Token,
+		CallTimeout: config.CallTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{
+		config: config,
+		sender: sender.(*net.RemoteNodeSenderGrpc),
+		owners: chord.NewLocationCache(config.CacheTTL),
+	}
+	client.seeds = seeds
+	return client, nil
+}
+
+// Close closes connections to the nodes
+func (c *Client) Close() {
+	c.sender.Close()
+}
+
+// Put stores value of the key
+func (c *Client) Put(key string, value []byte, options ...Option) error {
+	return c.write(&chord.Record{
+		CreationTime: time.Now(),
+		Content:      value,
+		Identifier:   chord.RecordIdentifier(c.config.Namespace, key),
+		Namespace:    c.config.Namespace,
+		Key:          key,
+	}, options)
+}
+
+// Delete deletes the key, a tombstone is stored so replicas don't bring it back
+func (c *Client) Delete(key string, options ...Option) error {
+	return c.write(chord.NewTombstone(c.config.Namespace, key), options)
+}
+
+// Get returns value of the key, ErrNotFound if it's missing
+func (c *Client) Get(key string, options ...Option) ([]byte, error) {
+	record, err := c.GetRecord(key, options...)
+	if err != nil {
+		return nil, err
+	}
+	return record.Content, nil
+}
+
+// GetRecord returns the newest record of the key among the replicas, ErrNotFound if it's missing
+// content is encrypted if the replica doesn't have the encryption key
+func (c *Client) GetRecord(key string, options ...Option) (*chord.Record, error) {
+	identifier := chord.RecordIdentifier(c.config.Namespace, key)
+	result, err := c.run(options, func(sender *net.RemoteNodeSenderGrpc, options Options) (interface{}, error) {
+		owner, err := c.owner(sender, identifier)
+		if err != nil {
+			return nil, err
+		}
+		replicas, err := c.replicas(owner, options)
+		if err != nil {
+			return nil, c.failed(identifier, owner, err)
+		}
+		records := make([]*chord.Record, len(replicas))
+		errs := each(replicas, func(i int, replica *chord.RemoteNode) error {
+			data, err := replica.Fetch(c.config.Namespace, identifier)
+			if err != nil || len(data) == 0 {
+				return err
+			}
+			record := &chord.Record{}
+			if err := json.Unmarshal(data, record); err != nil {
+				return err
+			}
+			records[i] = record
+			return nil
+		})
+		if errs[0] != nil {
+			// other replicas can still make the quorum, unless the owner is changed
+			if err := c.failed(identifier, owner, errs[0]); err == chord.ErrNotOwner || options.Consistency == ConsistencyOne {
+				return nil, err
+			}
+		}
+		if err := acknowledged(errs, options.Consistency.required(len(replicas))); err != nil {
+			return nil, err
+		}
+		// last writer wins
+		var newest *chord.Record
+		for _, record := range records {
+			if record != nil && (newest == nil || record.NewerThan(newest)) {
+				newest = record
+			}
+		}
+		if newest == nil || newest.Deleted {
+			return nil, ErrNotFound
+		}
+		return newest, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*chord.Record), nil
+}
+
+// Lookup returns the owner of the key
+func (c *Client) Lookup(key string, options ...Option) (*chord.Node, error) {
+	identifier := chord.RecordIdentifier(c.config.Namespace, key)
+	result, err := c.run(options, func(sender *net.RemoteNodeSenderGrpc, options Options) (interface{}, error) {
+		return c.owner(sender, identifier)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*chord.RemoteNode).Node, nil
+}
+
+// Status returns the view of the first reachable seed on the ring
+func (c *Client) Status(options ...Option) (*chord.RingStatus, error) {
+	result, err := c.run(options, func(sender *net.RemoteNodeSenderGrpc, options Options) (interface{}, error) {
+		var err error = ErrNoSeed
+		for _, seed := range c.seeds {
+			var ringStatus *chord.RingStatus
+			if ringStatus, err = sender.GetStatus(chord.NewRemoteNode(seed, sender)); err == nil {
+				return ringStatus, nil
+			}
+		}
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*chord.RingStatus), nil
+}
+
+// Ring returns the view of each node on the ring, following successors from the first reachable seed
+func (c *Client) Ring(options ...Option) ([]*chord.RingStatus, error) {
+	first, err := c.Status(options...)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []*chord.RingStatus{first}
+	seen := map[[helpers.HashSize]byte]bool{first.Node.Identifier: true}
+	current := first
+	for hop := 0; hop < ringMaxHops; hop++ {
+		successor := current.Successor
+		result, err := c.run(options, func(sender *net.RemoteNodeSenderGrpc, options Options) (interface{}, error) {
+			return sender.GetStatus(chord.NewRemoteNode(successor, sender))
+		})
+		if err != nil {
+			return statuses, err
+		}
+		current = result.(*chord.RingStatus)
+		if seen[current.Node.Identifier] {
+			break
+		}
+		seen[current.Node.Identifier] = true
+		statuses = append(statuses, current)
+	}
+	return statuses, nil
+}
+
+// Scan calls fn for the records of the namespace in ring order until fn returns false
+// records are read from the owners of the ranges, consistency level is not applied
+// deleted records and records which don't match their key are skipped
+func (c *Client) Scan(fn func(record *chord.Record) bool, options ...Option) error {
+	statuses, err := c.Ring(options...)
+	if err != nil {
+		return err
+	}
+	for i, ringStatus := range statuses {
+		// range of each node starts after the previous node of the walk, even if its predecessor is not updated yet
+		previous := statuses[(i+len(statuses)-1)%len(statuses)]
+		node := ringStatus.Node
+		from := previous.Node.Identifier
+		to := ringStatus.Node.Identifier
+		for {
+			result, err := c.run(options, func(sender *net.RemoteNodeSenderGrpc, options Options) (interface{}, error) {
+				return sender.Scan(chord.NewRemoteNode(node, sender), c.config.Namespace, from, to, scanPage)
+			})
+			if err != nil {
+				return err
+			}
+			records := result.([]*chord.Record)
+			if len(records) == 0 {
+				break
+			}
+			for _, record := range records {
+				if record.Deleted || (record.Key != "" && chord.RecordIdentifier(c.config.Namespace, record.Key) != record.Identifier) {
+					continue
+				}
+				if !fn(record) {
+					return nil
+				}
+			}
+			from = records[len(records)-1].Identifier
+			if from == to {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// write stores the record in its replicas
+func (c *Client) write(record *chord.Record, options []Option) error {
+	if c.config.Identity != nil {
+		record.Sign(c.config.Identity)
+	}
+	data := record.GetJson()
+	_, err := c.run(options, func(sender *net.RemoteNodeSenderGrpc, options Options) (interface{}, error) {
+		owner, err := c.owner(sender, record.Identifier)
+		if err != nil {
+			return nil, err
+		}
+		replicas, err := c.replicas(owner, options)
+		if err != nil {
+			return nil, c.failed(record.Identifier, owner, err)
+		}
+		errs := each(replicas, func(i int, replica *chord.RemoteNode) error {
+			_, err := replica.Store(data)
+			return err
+		})
+		if errs[0] != nil {
+			// other replicas can still make the quorum, unless the owner is changed
+			if err := c.failed(record.Identifier, owner, errs[0]); err == chord.ErrNotOwner || options.Consistency == ConsistencyOne {
+				return nil, err
+			}
+		}
+		return nil, acknowledged(errs, options.Consistency.required(len(replicas)))
+	})
+	return err
+}
+
+// owner finds the node responsible for the identifier through the seeds
+// returned node calls the rpcs through the sender of the operation
+func (c *Client) owner(sender *net.RemoteNodeSenderGrpc, identifier [helpers.HashSize]byte) (*chord.RemoteNode, error) {
+	if owner := c.owners.Lookup(identifier); owner != nil {
+		return chord.NewRemoteNode(owner.Node, sender), nil
+	}
+	var err error = ErrNoSeed
+	for _, seed := range c.seeds {
+		var owner *chord.RemoteNode
+		if owner, err = chord.NewRemoteNode(seed, sender).FindSuccessor(identifier); err == nil {
+			c.owners.Add(identifier, owner)
+			return owner, nil
+		}
+	}
+	return nil, err
+}
+
+// replicas returns the owner and its successors which keep replicas of its range
+// only the owner is returned if consistency level is one
+func (c *Client) replicas(owner *chord.RemoteNode, options Options) ([]*chord.RemoteNode, error) {
+	nodes := []*chord.RemoteNode{owner}
+	if options.Consistency == ConsistencyOne {
+		return nodes, nil
+	}
+	successorList, err := owner.GetSuccessorList()
+	if err != nil {
+		return nil, err
+	}
+	for _, successor := range successorList.GetNodes() {
+		// ring can be smaller than replicas
+		if len(nodes) >= c.config.Replicas || successor.Identifier == owner.Identifier {
+			break
+		}
+		nodes = append(nodes, successor)
+	}
+	return nodes, nil
+}
+
+// failed invalidates cached owner if it rejected the request or it's not reachable
+func (c *Client) failed(identifier [helpers.HashSize]byte, owner *chord.RemoteNode, err error) error {
+	if err == chord.ErrNotOwner {
+		c.owners.Invalidate(identifier)
+	} else {
+		c.owners.InvalidateOwner(owner.Identifier)
+	}
+	return err
+}
+
+// run retries the operation with exponential backoff until it succeeds or fails permanently
+// rpcs of the operation are sent by a sender bound to its deadline, so when ErrTimeout is returned
+// in-flight rpcs are cancelled and nothing is retried later
+func (c *Client) run(options []Option, operation func(sender *net.RemoteNodeSenderGrpc, options Options) (interface{}, error)) (interface{}, error) {
+	opts := c.config.Options
+	for _, option := range options {
+		option(&opts)
+	}
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	sender := c.sender.WithContext(ctx)
+	backoff := opts.Backoff
+	var result interface{}
+	var err error
+	for attempt := 0; attempt < opts.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ErrTimeout
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+		result, err = operation(sender, opts)
+		if err != nil && ctx.Err() != nil {
+			return nil, ErrTimeout
+		}
+		if err == nil || !retryable(err) {
+			break
+		}
+	}
+	return result, err
+}
+
+// retryable check if the operation can succeed in another attempt
+// e.g. owner is changed or replicas are not reachable, but not rejected requests
+func retryable(err error) bool {
+	switch err {
+	case ErrNotFound, chord.ErrInvalidSignature, net.ErrUnsupported, net.ErrIncompatibleVersion:
+		return false
+	}
+	switch status.Code(err) {
+	case codes.PermissionDenied, codes.Unauthenticated, codes.InvalidArgument:
+		return false
+	}
+	return true
+}
+
+// each calls fn for all nodes concurrently and returns their errors in the same order
+func each(nodes []*chord.RemoteNode, fn func(i int, node *chord.RemoteNode) error) []error {
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *chord.RemoteNode) {
+			defer wg.Done()
+			errs[i] = fn(i, node)
+		}(i, node)
+	}
+	wg.Wait()
+	return errs
+}
+
+// acknowledged check if at least required nodes succeeded
+func acknowledged(errs []error, required int) error {
+	acks := 0
+	var failure error
+	for _, err := range errs {
+		if err == nil {
+			acks++
+		} else {
+			failure = err
+		}
+	}
+	if acks >= required {
+		return nil
+	}
+	if len(errs) == 1 {
+		return failure
+	}
+	return ErrNotEnoughReplicas
+}
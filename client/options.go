@@ -0,0 +1,91 @@
+package client
+
+import "time"
+
+// default options of operations
+const (
+	defaultTimeout     time.Duration = 5 * time.Second
+	defaultMaxAttempts int           = 3
+	defaultBackoff     time.Duration = 100 * time.Millisecond
+)
+
+// Consistency is the number of replicas which must acknowledge an operation
+type Consistency int
+
+const (
+	ConsistencyOne    Consistency = iota // only the owner
+	ConsistencyQuorum                    // majority of the replicas
+	ConsistencyAll                       // all replicas
+)
+
+// ParseConsistency converts name of the consistency level (one, quorum, all)
+func ParseConsistency(name string) (Consistency, bool) {
+	switch name {
+	case "one":
+		return ConsistencyOne, true
+	case "quorum":
+		return ConsistencyQuorum, true
+	case "all":
+		return ConsistencyAll, true
+	}
+	return ConsistencyOne, false
+}
+
+// required returns the number of acknowledgements out of the replicas
+func (c Consistency) required(replicas int) int {
+	switch c {
+	case ConsistencyQuorum:
+		return replicas/2 + 1
+	case ConsistencyAll:
+		return replicas
+	}
+	return 1
+}
+
+// Options of an operation, zero values are replaced by defaults
+type Options struct {
+	Consistency Consistency
+	// Timeout is the deadline of the operation including its retries
+	Timeout time.Duration
+	// MaxAttempts including the first attempt
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled after each retry
+	Backoff time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout == 0 {
+		o.Timeout = defaultTimeout
+	}
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = defaultMaxAttempts
+	}
+	if o.Backoff == 0 {
+		o.Backoff = defaultBackoff
+	}
+	return o
+}
+
+// Option overrides options of the client for a single operation
+type Option func(options *Options)
+
+// WithConsistency sets the consistency level of the operation
+func WithConsistency(consistency Consistency) Option {
+	return func(options *Options) {
+		options.Consistency = consistency
+	}
+}
+
+// WithTimeout sets the deadline of the operation including its retries
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *Options) {
+		options.Timeout = timeout
+	}
+}
+
+// WithMaxAttempts sets the number of attempts including the first one
+func WithMaxAttempts(attempts int) Option {
+	return func(options *Options) {
+		options.MaxAttempts = attempts
+	}
+}
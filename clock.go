@@ -0,0 +1,16 @@
+package chord
+
+import "time"
+
+// Clock is the source of time of the ring
+// so rings can be driven by a virtual clock in simulations
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock uses the wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
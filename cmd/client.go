@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/client"
+	"github.com/mbrostami/chord/net"
+	log "github.com/sirupsen/logrus"
+)
+
+// cli is a client of the ring for a single command
+type cli struct {
+	*client.Client
+	namespace string
+	json      bool
+	out       io.Writer
+}
+
+// newClient parses client flags and makes a client of the nodes
+// flags are overridden by CHORD_* environment variables, e.g. CHORD_NODE
+func newClient(name string, args []string, arguments string) (*cli, []string, error) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags] %s\n", os.Args[0], name, arguments)
+		flags.PrintDefaults()
+	}
+	config := client.Config{}
+	nodes := flags.String("node", "127.0.0.1:10001", "comma separated node addresses, tried in order")
+	flags.StringVar(&config.Namespace, "namespace", "", "namespace of the keys")
+	jsonOutput := flags.Bool("json", false, "print json output")
+	logLevel := flags.String("log-level", "fatal", "log level, failures are printed anyway")
+	consistency := flags.String("consistency", "one", "number of replicas to acknowledge reads and writes (one, quorum, all)")
+	flags.IntVar(&config.Replicas, "replicas", chord.REPLICAS, "replicas of the ring")
+	flags.DurationVar(&config.Timeout, "timeout", 5*time.Second, "deadline of each operation including retries")
+	flags.IntVar(&config.MaxAttempts, "max-attempts", 3, "attempts of each operation")
+	tlsCert := flags.String("tls-cert", "", "tls certificate file of the client")
+	tlsKey := flags.String("tls-key", "", "tls private key file of the client")
+	tlsCA := flags.String("tls-ca", "", "CA bundle to verify nodes, enables tls")
+	flags.StringVar(&config.Token, "token", "", "bearer token to authenticate to the nodes")
+	identityKey := flags.String("identity-key", "", "ed25519 key file (created if missing) to sign stored records")
+	if err := applyEnv(flags); err != nil {
+		return nil, nil, err
+	}
+	flags.Parse(args)
+	level, err := log.ParseLevel(*logLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.SetLevel(level)
+	var found bool
+	if config.Consistency, found = client.ParseConsistency(*consistency); !found {
+		return nil, nil, fmt.Errorf("unknown consistency level %q", *consistency)
+	}
+	if *tlsCert != "" || *tlsCA != "" {
+		config.TLS = &net.TLSConfig{
+			CertFile: *tlsCert,
+			KeyFile:  *tlsKey,
+			CAFile:   *tlsCA,
+		}
+	}
+	if *identityKey != "" {
+		if config.Identity, err = net.LoadIdentity(*identityKey); err != nil {
+			return nil, nil, err
+		}
+	}
+	config.Seeds = strings.Split(*nodes, ",")
+	c, err := client.New(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &cli{
+		Client:    c,
+		namespace: config.Namespace,
+		json:      *jsonOutput,
+		out:       os.Stdout,
+	}, flags.Args(), nil
+}
+
+// print writes value as json, or the text if json output is not requested
+func (c *cli) print(value interface{}, text string) error {
+	if !c.json {
+		_, err := fmt.Fprintln(c.out, text)
+		return err
+	}
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+type nodeOutput struct {
+	Address    string `json:"address"`
+	Identifier string `json:"identifier"`
+}
+
+func newNodeOutput(node *chord.Node) *nodeOutput {
+	if node == nil {
+		return nil
+	}
+	return &nodeOutput{
+		Address:    node.GetFullAddress(),
+		Identifier: hex.EncodeToString(node.Identifier[:]),
+	}
+}
+
+func (n *nodeOutput) String() string {
+	if n == nil {
+		return "unknown"
+	}
+	return n.Address + " " + n.Identifier
+}
+
+type keyOutput struct {
+	Key          string      `json:"key"`
+	Namespace    string      `json:"namespace,omitempty"`
+	Identifier   string      `json:"identifier"`
+	Owner        *nodeOutput `json:"owner,omitempty"`
+	Value        *string     `json:"value,omitempty"`
+	CreationTime *time.Time  `json:"creation_time,omitempty"`
+	Encrypted    bool        `json:"encrypted,omitempty"` // the node doesn't have the encryption key
+	Deleted      bool        `json:"deleted,omitempty"`
+}
+
+// newKeyOutput makes output of the key, owner is taken from the cache of the previous operation
+func (c *cli) newKeyOutput(key string) *keyOutput {
+	identifier := chord.RecordIdentifier(c.namespace, key)
+	output := &keyOutput{
+		Key:        key,
+		Namespace:  c.namespace,
+		Identifier: hex.EncodeToString(identifier[:]),
+	}
+	if owner, err := c.Lookup(key); err == nil {
+		output.Owner = newNodeOutput(owner)
+	}
+	return output
+}
+
+// put stores value of the key
+func put(args []string) error {
+	c, args, err := newClient("put", args, "<key> [value]")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("put requires a key and a value")
+	}
+	var value []byte
+	if len(args) == 2 {
+		value = []byte(args[1])
+	} else if value, err = ioutil.ReadAll(os.Stdin); err != nil {
+		return err
+	}
+	if err := c.Put(args[0], value); err != nil {
+		return fmt.Errorf("storing %q failed: %v", args[0], err)
+	}
+	output := c.newKeyOutput(args[0])
+	return c.print(output, fmt.Sprintf("stored %s at %s", args[0], output.Owner))
+}
+
+// get prints value of the key
+func get(args []string) error {
+	c, args, err := newClient("get", args, "<key>")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if len(args) != 1 {
+		return errors.New("get requires a key")
+	}
+	record, err := c.GetRecord(args[0])
+	if err != nil {
+		return err
+	}
+	output := c.newKeyOutput(args[0])
+	value := string(record.Content)
+	output.Value = &value
+	output.CreationTime = &record.CreationTime
+	output.Encrypted = record.IsEncrypted()
+	return c.print(output, value)
+}
+
+// remove deletes the key
+func remove(args []string) error {
+	c, args, err := newClient("delete", args, "<key>")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if len(args) != 1 {
+		return errors.New("delete requires a key")
+	}
+	if err := c.Delete(args[0]); err != nil {
+		return fmt.Errorf("deleting %q failed: %v", args[0], err)
+	}
+	output := c.newKeyOutput(args[0])
+	output.Deleted = true
+	return c.print(output, fmt.Sprintf("deleted %s at %s", args[0], output.Owner))
+}
+
+// lookup prints owner of the key
+func lookup(args []string) error {
+	c, args, err := newClient("lookup", args, "<key>")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if len(args) != 1 {
+		return errors.New("lookup requires a key")
+	}
+	output := c.newKeyOutput(args[0])
+	if output.Owner == nil {
+		return fmt.Errorf("lookup of %q failed", args[0])
+	}
+	return c.print(output, output.Owner.String())
+}
+
+// scan prints keys and values of the namespace in ring order
+func scan(args []string) error {
+	c, _, err := newClient("scan", args, "")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	outputs := []*keyOutput{}
+	lines := []string{}
+	err = c.Scan(func(record *chord.Record) bool {
+		value := string(record.Content)
+		creationTime := record.CreationTime
+		outputs = append(outputs, &keyOutput{
+			Key:          record.Key,
+			Namespace:    record.Namespace,
+			Identifier:   hex.EncodeToString(record.Identifier[:]),
+			Value:        &value,
+			CreationTime: &creationTime,
+			Encrypted:    record.IsEncrypted(),
+		})
+		lines = append(lines, fmt.Sprintf("%s\t%s", record.Key, strings.TrimSuffix(value, "\n")))
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return c.print(outputs, strings.Join(lines, "\n"))
+}
+
+type fingerOutput struct {
+	Index int         `json:"index"`
+	Node  *nodeOutput `json:"node"`
+}
+
+type statusOutput struct {
+	Node            *nodeOutput     `json:"node"`
+	Successor       *nodeOutput     `json:"successor"`
+	Predecessor     *nodeOutput     `json:"predecessor"`
+	SuccessorList   []*nodeOutput   `json:"successor_list"`
+	PredecessorList []*nodeOutput   `json:"predecessor_list"`
+	Fingers         []*fingerOutput `json:"fingers"`
+}
+
+func newStatusOutput(status *chord.RingStatus) *statusOutput {
+	output := &statusOutput{
+		Node:            newNodeOutput(status.Node),
+		Successor:       newNodeOutput(status.Successor),
+		Predecessor:     newNodeOutput(status.Predecessor),
+		SuccessorList:   []*nodeOutput{},
+		PredecessorList: []*nodeOutput{},
+		Fingers:         []*fingerOutput{},
+	}
+	for _, node := range status.SuccessorList {
+		output.SuccessorList = append(output.SuccessorList, newNodeOutput(node))
+	}
+	for _, node := range status.PredecessorList {
+		output.PredecessorList = append(output.PredecessorList, newNodeOutput(node))
+	}
+	for index, node := range status.Fingers {
+		output.Fingers = append(output.Fingers, &fingerOutput{Index: index, Node: newNodeOutput(node)})
+	}
+	sort.Slice(output.Fingers, func(i, j int) bool {
+		return output.Fingers[i].Index < output.Fingers[j].Index
+	})
+	return output
+}
+
+func (s *statusOutput) String() string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "node:        %s\n", s.Node)
+	fmt.Fprintf(&text, "successor:   %s\n", s.Successor)
+	fmt.Fprintf(&text, "predecessor: %s\n", s.Predecessor)
+	fmt.Fprintln(&text, "successor list:")
+	for i, node := range s.SuccessorList {
+		fmt.Fprintf(&text, "  %d %s\n", i, node)
+	}
+	fmt.Fprintln(&text, "predecessor list:")
+	for i, node := range s.PredecessorList {
+		fmt.Fprintf(&text, "  %d %s\n", i, node)
+	}
+	fmt.Fprintln(&text, "fingers:")
+	for _, finger := range s.Fingers {
+		fmt.Fprintf(&text, "  %d %s\n", finger.Index, finger.Node)
+	}
+	return strings.TrimSuffix(text.String(), "\n")
+}
+
+// nodeStatus prints the view of the node on the ring
+func nodeStatus(args []string) error {
+	c, _, err := newClient("status", args, "")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	status, err := c.Status()
+	if err != nil {
+		return err
+	}
+	output := newStatusOutput(status)
+	return c.print(output, output.String())
+}
+
+// walkRing prints nodes of the ring in order, following successors from the node
+func walkRing(args []string) error {
+	c, _, err := newClient("ring", args, "")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	statuses, err := c.Ring()
+	if err != nil {
+		return fmt.Errorf("walking the ring stopped after %d nodes: %v", len(statuses), err)
+	}
+	nodes := []*nodeOutput{}
+	lines := []string{}
+	for _, status := range statuses {
+		node := newNodeOutput(status.Node)
+		nodes = append(nodes, node)
+		lines = append(lines, node.String())
+	}
+	return c.print(nodes, strings.Join(lines, "\n"))
+}
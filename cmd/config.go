@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mbrostami/chord"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// configEnv is the environment variable of the config file
+const configEnv string = "CHORD_CONFIG"
+
+// envPrefix is the prefix of environment variables overriding flags, e.g. CHORD_PORT overrides -port
+const envPrefix string = "CHORD_"
+
+// envExcluded flags are not overridden by environment variables
+// CHORD_ENCRYPTION_KEYS holds the keys themselves, not the file
+var envExcluded = map[string]bool{
+	"encryption-keys": true,
+}
+
+// config of the node
+// loaded from yaml file, then overridden by CHORD_* environment variables and then by flags
+type config struct {
+	IP       string `yaml:"ip"`
+	Port     int    `yaml:"port"`
+	LogLevel string `yaml:"log_level"`
+
+	Create      bool          `yaml:"create"`
+	Join        string        `yaml:"join"` // comma separated seeds
+	JoinRetries int           `yaml:"join_retries"`
+	JoinBackoff time.Duration `yaml:"join_backoff"`
+
+	EncryptionKeys string `yaml:"encryption_keys"` // file of encryption keys
+	TrustedWriters string `yaml:"trusted_writers"` // file of public keys allowed to sign records
+	MaxConnections int    `yaml:"max_connections"`
+	Faults         string `yaml:"faults"` // file of fault injection rules
+	HTTP           string `yaml:"http"`   // listen address of the http gateway, empty disables it
+
+	TLS       tlsConfig    `yaml:"tls"`
+	Auth      authConfig   `yaml:"auth"`
+	Intervals intervals    `yaml:"intervals"`
+	Ring      chord.Config `yaml:"ring"`
+}
+
+type tlsConfig struct {
+	Cert   string `yaml:"cert"`
+	Key    string `yaml:"key"`
+	CA     string `yaml:"ca"`
+	Mutual bool   `yaml:"mutual"`
+}
+
+type authConfig struct {
+	IdentityKey       string `yaml:"identity_key"`
+	VerifyPeerAddress bool   `yaml:"verify_peer_address"`
+	RequireSignature  bool   `yaml:"require_signature"`
+	Tokens            string `yaml:"tokens"` // json file of client tokens
+	Cert              bool   `yaml:"cert"`
+	Token             string `yaml:"token"`
+	ACL               bool   `yaml:"acl"`
+	ACLAdmins         string `yaml:"acl_admins"` // comma separated identities
+}
+
+// intervals of the maintenance loops
+type intervals struct {
+	FixFingers       time.Duration `yaml:"fix_fingers"`
+	CheckPredecessor time.Duration `yaml:"check_predecessor"`
+	Stabilize        time.Duration `yaml:"stabilize"`
+	ProbeMembers     time.Duration `yaml:"probe_members"`
+	SyncData         time.Duration `yaml:"sync_data"`
+	Reencrypt        time.Duration `yaml:"reencrypt"`
+	Verbose          time.Duration `yaml:"verbose"`
+}
+
+func defaultConfig() *config {
+	return &config{
+		IP:          "127.0.0.1",
+		Port:        10001,
+		LogLevel:    "info",
+		JoinRetries: 5,
+		JoinBackoff: 1 * time.Second,
+		Intervals: intervals{
+			FixFingers:       1 * time.Second,
+			CheckPredecessor: 1 * time.Second,
+			Stabilize:        1 * time.Second,
+			ProbeMembers:     5 * time.Second,
+			SyncData:         10 * time.Second,
+			Reencrypt:        1 * time.Minute,
+			Verbose:          5 * time.Second,
+		},
+		Ring: chord.DefaultConfig(),
+	}
+}
+
+// loadConfig builds config of the node from defaults, config file, environment variables and flags
+// later sources override the former ones
+func loadConfig(flags *flag.FlagSet, args []string) (*config, error) {
+	cfg := defaultConfig()
+	file := configFile(args)
+	if file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+			return nil, fmt.Errorf("config %s: %v", file, err)
+		}
+	}
+	// flags are bound after loading the file, so their defaults are the file's values
+	flags.String("config", file, "yaml config file, or set "+configEnv)
+	verbosity := cfg.bind(flags)
+	if err := applyEnv(flags); err != nil {
+		return nil, err
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if verbosity.debug {
+		cfg.LogLevel = "debug"
+	} else if verbosity.info {
+		cfg.LogLevel = "info"
+	} else if verbosity.warning {
+		cfg.LogLevel = "warning"
+	}
+	// trusted writers are part of ring tunables, so they are loaded before validation
+	if cfg.TrustedWriters != "" {
+		writers, err := chord.LoadWriterKeys(cfg.TrustedWriters)
+		if err != nil {
+			return nil, fmt.Errorf("trusted writers %s: %v", cfg.TrustedWriters, err)
+		}
+		cfg.Ring.TrustedWriters = writers
+	}
+	return cfg, cfg.validate()
+}
+
+type verbosity struct {
+	warning, info, debug bool
+}
+
+// bind defines flags which write into config
+func (c *config) bind(flags *flag.FlagSet) *verbosity {
+	v := &verbosity{}
+	flags.BoolVar(&v.warning, "v", false, "verbose (warning)")
+	flags.BoolVar(&v.info, "vv", false, "verbose (info)")
+	flags.BoolVar(&v.debug, "vvv", false, "verbose (debug)")
+	flags.StringVar(&c.LogLevel, "log-level", c.LogLevel, "log level (debug, info, warning, error)")
+	flags.StringVar(&c.IP, "ip", c.IP, "ip address")
+	flags.IntVar(&c.Port, "port", c.Port, "port number")
+	flags.BoolVar(&c.Create, "create", c.Create, "create a new ring")
+	flags.StringVar(&c.Join, "join", c.Join, "comma separated seeds (ip:port or dns-name:port) to join an existing ring, tried in order")
+	flags.IntVar(&c.JoinRetries, "join-retries", c.JoinRetries, "number of retries if all seeds fail")
+	flags.DurationVar(&c.JoinBackoff, "join-backoff", c.JoinBackoff, "wait before the first retry of joining, doubled after each retry")
+
+	flags.StringVar(&c.EncryptionKeys, "encryption-keys", c.EncryptionKeys, "file of encryption keys (id:base64key per line, first is active), or set "+encryptionKeysEnv)
+	flags.StringVar(&c.TrustedWriters, "trusted-writers", c.TrustedWriters, "file of ed25519 public keys (base64 per line) allowed to sign records, required by -require-signed-records")
+	flags.IntVar(&c.MaxConnections, "max-connections", c.MaxConnections, "maximum open connections to other nodes, 0 means unlimited")
+	flags.StringVar(&c.HTTP, "http", c.HTTP, "listen address of the http gateway (e.g. 127.0.0.1:8080), disabled if empty")
+	flags.StringVar(&c.Faults, "faults", c.Faults, "json file of fault injection rules, enables injection (rules can be changed by admins at runtime)")
+
+	flags.StringVar(&c.TLS.Cert, "tls-cert", c.TLS.Cert, "tls certificate file, enables tls")
+	flags.StringVar(&c.TLS.Key, "tls-key", c.TLS.Key, "tls private key file")
+	flags.StringVar(&c.TLS.CA, "tls-ca", c.TLS.CA, "CA bundle to verify peers (default system pool)")
+	flags.BoolVar(&c.TLS.Mutual, "mtls", c.TLS.Mutual, "require peer certificates matching the node address")
+
+	flags.StringVar(&c.Auth.IdentityKey, "identity-key", c.Auth.IdentityKey, "ed25519 key file (created if missing), derives node identifier from public key")
+	flags.BoolVar(&c.Auth.VerifyPeerAddress, "verify-peer-address", c.Auth.VerifyPeerAddress, "reject callers claiming an address different from the connection")
+	flags.BoolVar(&c.Auth.RequireSignature, "require-signature", c.Auth.RequireSignature, "reject callers without signed node claims")
+	flags.StringVar(&c.Auth.Tokens, "auth-tokens", c.Auth.Tokens, "json file of client tokens {\"identity\": \"token\"}, enables authentication")
+	flags.BoolVar(&c.Auth.Cert, "auth-cert", c.Auth.Cert, "authenticate clients by mutual tls certificate")
+	flags.StringVar(&c.Auth.Token, "token", c.Auth.Token, "bearer token to authenticate to other nodes")
+	flags.BoolVar(&c.Auth.ACL, "acl", c.Auth.ACL, "authorize clients by namespace access control lists")
+	flags.StringVar(&c.Auth.ACLAdmins, "acl-admins", c.Auth.ACLAdmins, "comma separated identities with admin permission in all namespaces (include other nodes)")
+
+	flags.DurationVar(&c.Intervals.FixFingers, "fix-fingers", c.Intervals.FixFingers, "interval between finger table refresh rounds")
+	flags.DurationVar(&c.Intervals.CheckPredecessor, "check-predecessor", c.Intervals.CheckPredecessor, "interval between predecessor checks")
+	flags.DurationVar(&c.Intervals.Stabilize, "stabilize", c.Intervals.Stabilize, "interval between stabilization rounds")
+	flags.DurationVar(&c.Intervals.ProbeMembers, "probe-members", c.Intervals.ProbeMembers, "interval between probes of remembered members")
+	flags.DurationVar(&c.Intervals.SyncData, "sync-data", c.Intervals.SyncData, "interval between data synchronizations with successor")
+	flags.DurationVar(&c.Intervals.Reencrypt, "reencrypt", c.Intervals.Reencrypt, "interval between re-encryption rounds")
+	flags.DurationVar(&c.Intervals.Verbose, "verbose-interval", c.Intervals.Verbose, "interval between printing ring state")
+
+	flags.IntVar(&c.Ring.Replicas, "replicas", c.Ring.Replicas, "number of nodes keeping each record, must be the same in all nodes")
+	flags.IntVar(&c.Ring.SuccessorListSize, "successor-list-size", c.Ring.SuccessorListSize, "number of nodes in successor and predecessor lists, must be the same in all nodes")
+	flags.Float64Var(&c.Ring.PhiThreshold, "phi-threshold", c.Ring.PhiThreshold, "suspicion level to consider a node as failed")
+	flags.StringVar(&c.Ring.DataDir, "data-dir", c.Ring.DataDir, "directory of the database")
+	flags.DurationVar(&c.Ring.LocationCacheTTL, "location-cache-ttl", c.Ring.LocationCacheTTL, "expiration of cached lookup results")
+	flags.DurationVar(&c.Ring.MemberTTL, "member-ttl", c.Ring.MemberTTL, "how long members are remembered after they were last seen")
+	flags.BoolVar(&c.Ring.RequireSignedRecords, "require-signed-records", c.Ring.RequireSignedRecords, "reject records which are not signed by trusted writers")
+	return v
+}
+
+// validate checks config, ring tunables are checked too
+func (c *config) validate() error {
+	if c.Create == (c.Join != "") {
+		return errors.New("either create or join is required")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port %d is out of range", c.Port)
+	}
+	if _, err := log.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if c.JoinRetries < 0 || c.JoinBackoff < 0 {
+		return errors.New("join retries and backoff must not be negative")
+	}
+	durations := map[string]time.Duration{
+		"fix fingers":       c.Intervals.FixFingers,
+		"check predecessor": c.Intervals.CheckPredecessor,
+		"stabilize":         c.Intervals.Stabilize,
+		"probe members":     c.Intervals.ProbeMembers,
+		"sync data":         c.Intervals.SyncData,
+		"reencrypt":         c.Intervals.Reencrypt,
+		"verbose":           c.Intervals.Verbose,
+	}
+	for name, duration := range durations {
+		if duration <= 0 {
+			return fmt.Errorf("%s interval must be positive", name)
+		}
+	}
+	// records stored through the gateway are signed by the node identity
+	if c.HTTP != "" && c.Ring.RequireSignedRecords && c.Auth.IdentityKey == "" {
+		return errors.New("http gateway requires identity key to sign records when signed records are required")
+	}
+	return c.Ring.Validate()
+}
+
+// configFile finds the config file in arguments or environment variable
+// it's needed before parsing flags, since flags override the file
+func configFile(args []string) string {
+	for i, arg := range args {
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config=")
+		}
+	}
+	return os.Getenv(configEnv)
+}
+
+// applyEnv sets flags from CHORD_* environment variables, e.g. CHORD_JOIN_RETRIES sets -join-retries
+func applyEnv(flags *flag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if envExcluded[f.Name] || f.Name == "config" || err != nil {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.Replace(f.Name, "-", "_", -1))
+		if value, found := os.LookupEnv(name); found {
+			if setErr := flags.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("%s: %v", name, setErr)
+			}
+		}
+	})
+	return err
+}
@@ -1,104 +1,267 @@
 package main
 
 import (
-	"bufio"
+	"crypto/ed25519"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/mbrostami/chord"
-	"github.com/mbrostami/chord/helpers"
+	"github.com/mbrostami/chord/fault"
 	"github.com/mbrostami/chord/net"
 	log "github.com/sirupsen/logrus"
 )
 
-func main() {
-	logLevelWarning := flag.Bool("v", false, "verbose (warning)")
-	logLevelInfo := flag.Bool("vv", false, "verbose (info)")
-	logLevelDebug := flag.Bool("vvv", false, "verbose (debug)")
-	ip := flag.String("ip", "127.0.0.1", "ip address")
-	port := flag.Int("port", 0, "port number")
-	flag.Parse()
+// encryptionKeysEnv is the environment variable of encryption keys
+const encryptionKeysEnv string = "CHORD_ENCRYPTION_KEYS"
+
+// commands of the cli, serve runs a node and the others are clients of running nodes
+var commands = map[string]func(args []string) error{
+	"serve":  serve,
+	"put":    put,
+	"get":    get,
+	"delete": remove,
+	"lookup": lookup,
+	"scan":   scan,
+	"status": nodeStatus,
+	"ring":   walkRing,
+}
 
-	if *logLevelDebug {
-		log.SetLevel(log.DebugLevel)
-	} else if *logLevelInfo {
-		log.SetLevel(log.InfoLevel)
-	} else if *logLevelWarning {
-		log.SetLevel(log.WarnLevel)
+func main() {
+	args := os.Args[1:]
+	// node flags without a command are kept working for existing deployments
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"serve"}, args...)
 	}
+	command, found := commands[args[0]]
+	if !found {
+		usage()
+		os.Exit(2)
+	}
+	if err := command(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
-	remoteSender := net.NewRemoteNodeSenderGrpc()
-	var chordRing chord.RingInterface
-	var bootstrapNode *chord.RemoteNode
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s <command> [flags] [arguments]
 
-	if *port == 0 {
-		// Should be a Bootstrap server which is acting like a node
-		// with one more functionality to find a closest available node to the newly joining node
-		log.Info("Bootstrap Node")
-		node := chord.NewNode("127.0.0.1", 10001)
-		chordRing = chord.NewRing(node, remoteSender)
-	} else {
-		bootstrapNode = chord.NewRemoteNode(chord.NewNode("127.0.0.1", 10001), remoteSender)
-		node := chord.NewNode(*ip, uint(*port))
-		chordRing = chord.NewRing(node, remoteSender)
+Commands:
+  serve                  run a node
+  put <key> [value]      store value of the key, value is read from stdin if it's missing
+  get <key>              print value of the key
+  delete <key>           delete the key
+  lookup <key>           print owner of the key
+  scan                   print keys and values of the namespace in ring order
+  status                 print successor, predecessor, lists and finger table of the node
+  ring                   walk the ring through successors
+
+Run '%[1]s <command> -h' for flags of the command.
+`, os.Args[0])
+}
+
+// serve runs a node until it's killed
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	cfg, err := loadConfig(flags, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		os.Exit(2)
 	}
+	level, _ := log.ParseLevel(cfg.LogLevel)
+	log.SetLevel(level)
 
-	go net.NewChordReceiver(chordRing)
-	time.Sleep(5 * time.Second) // wait until grpc server is up
-	if *port != 0 {
-		chordRing.Join(bootstrapNode)
+	var tlsConfig *net.TLSConfig
+	if cfg.TLS.Cert != "" || cfg.TLS.CA != "" {
+		tlsConfig = &net.TLSConfig{
+			CertFile:  cfg.TLS.Cert,
+			KeyFile:   cfg.TLS.Key,
+			CAFile:    cfg.TLS.CA,
+			MutualTLS: cfg.TLS.Mutual,
+		}
+	}
+	var identity ed25519.PrivateKey
+	if cfg.Auth.IdentityKey != "" {
+		identity, err = net.LoadIdentity(cfg.Auth.IdentityKey)
+		if err != nil {
+			log.Fatalf("Error loading identity: %v", err)
+		}
+	}
+	var authenticator *net.Authenticator
+	if cfg.Auth.Tokens != "" || cfg.Auth.Cert {
+		tokens := make(map[string]string)
+		if cfg.Auth.Tokens != "" {
+			tokens, err = net.LoadTokens(cfg.Auth.Tokens)
+			if err != nil {
+				log.Fatalf("Error loading tokens: %v", err)
+			}
+		}
+		authenticator = net.NewAuthenticator(tokens, cfg.Auth.Cert)
+	}
+	localNode := newNode(cfg.IP, uint(cfg.Port), identity)
+	remoteSender, err := net.NewRemoteNodeSenderGrpc(net.SenderConfig{
+		TLS:       tlsConfig,
+		Identity:  identity,
+		LocalNode: localNode,
+		Token:     cfg.Auth.Token,
+		Pool:      net.PoolConfig{MaxConnections: cfg.MaxConnections},
+	})
+	if err != nil {
+		log.Fatalf("Error creating sender: %v", err)
+	}
+	var sender chord.RemoteNodeSenderInterface = remoteSender
+	var faults *fault.Injector
+	if cfg.Faults != "" {
+		rules, err := fault.LoadRules(cfg.Faults)
+		if err != nil {
+			log.Fatalf("Error loading fault rules: %v", err)
+		}
+		faults, _ = fault.NewInjector(rules, time.Now().UnixNano())
+		sender = fault.NewSender(remoteSender, faults)
+		log.Warnf("Fault injection is enabled with %d rules", len(rules))
+	}
+	cfg.Ring.KeyRing = loadKeyRing(cfg.EncryptionKeys)
+	chordRing, err := chord.NewRing(localNode, sender, cfg.Ring)
+	if err != nil {
+		log.Fatalf("Error creating ring: %v", err)
+	}
+	if cfg.Create {
+		log.Info("Creating a new ring")
+		chordRing.Create()
+	}
+
+	var accessController *net.AccessControl
+	if cfg.Auth.ACL {
+		accessController = net.NewAccessControl(chordRing, strings.Split(cfg.Auth.ACLAdmins, ","))
+	}
+	_, err = net.NewChordReceiver(chordRing, net.ReceiverConfig{
+		TLS:               tlsConfig,
+		VerifyPeerAddress: cfg.Auth.VerifyPeerAddress,
+		RequireSignature:  cfg.Auth.RequireSignature,
+		Authenticator:     authenticator,
+		AccessControl:     accessController,
+		Faults:            faults,
+	})
+	if err != nil {
+		log.Fatalf("Error starting server: %v", err)
+	}
+	if cfg.HTTP != "" {
+		_, err = net.NewHTTPGateway(chordRing, net.GatewayConfig{
+			Address:       cfg.HTTP,
+			TLS:           tlsConfig,
+			Authenticator: authenticator,
+			AccessControl: accessController,
+			Identity:      identity,
+		})
+		if err != nil {
+			log.Fatalf("Error starting http gateway: %v", err)
+		}
+	}
+	if cfg.Join != "" {
+		if err := joinRing(chordRing, sender, cfg.Join, cfg.JoinRetries, cfg.JoinBackoff); err != nil {
+			log.Fatalf("Error joining ring: %v", err)
+		}
 	}
 	go func() {
 		for {
 			chordRing.FixFingers()
-			time.Sleep(1 * time.Second)
+			time.Sleep(cfg.Intervals.FixFingers)
 		}
 	}()
 	go func() {
 		for {
 			chordRing.CheckPredecessor()
-			time.Sleep(1 * time.Second)
+			time.Sleep(cfg.Intervals.CheckPredecessor)
 		}
 	}()
 	go func() {
 		for {
 			chordRing.Stabilize()
-			time.Sleep(1 * time.Second)
+			time.Sleep(cfg.Intervals.Stabilize)
+		}
+	}()
+	go func() {
+		for {
+			chordRing.ProbeMembers()
+			time.Sleep(cfg.Intervals.ProbeMembers)
 		}
 	}()
 	go func() {
 		for {
 			chordRing.SyncData()
-			time.Sleep(10 * time.Second)
+			time.Sleep(cfg.Intervals.SyncData)
+		}
+	}()
+	go func() {
+		for {
+			chordRing.Reencrypt()
+			time.Sleep(cfg.Intervals.Reencrypt)
 		}
 	}()
 	log.Debugf("Current Node: %x", chordRing.GetLocalNode().Identifier)
 	go func() {
 		for {
 			chordRing.Verbose()
-			time.Sleep(5 * time.Second)
+			time.Sleep(cfg.Intervals.Verbose)
 		}
 	}()
-	if *port != 0 {
-		reader := bufio.NewReader(os.Stdin)
-		for {
-			log.Debugf("Current Node: %x", chordRing.GetLocalNode().Identifier)
-			fmt.Print("Enter value to store: ")
-			username, _ := reader.ReadString('\n')
-			record := &chord.Record{
-				CreationTime: time.Now(),
-				Content:      []byte(username),
-				Identifier:   helpers.Hash(username),
+	var wg sync.WaitGroup
+	wg.Add(1)
+	wg.Wait()
+	return nil
+}
+
+// joinRing joins the ring through the seeds, retries with exponential backoff if all seeds fail
+// seeds are resolved before each attempt, since seeds behind a dns name can change while the cluster is starting
+func joinRing(chordRing chord.RingInterface, sender chord.RemoteNodeSenderInterface, addresses string, retries int, backoff time.Duration) error {
+	for attempt := 0; ; attempt++ {
+		seeds, err := net.ResolveSeeds(addresses)
+		if err == nil {
+			remoteSeeds := make([]*chord.RemoteNode, 0, len(seeds))
+			for _, seed := range seeds {
+				remoteSeeds = append(remoteSeeds, chord.NewRemoteNode(seed, sender))
 			}
-			remoteNodeToStore := chordRing.FindSuccessor(record.Hash())
-			remoteNodeToStore.Store(record.GetJson())
+			if err = chordRing.Join(remoteSeeds...); err == nil {
+				return nil
+			}
+		}
+		if attempt >= retries {
+			return err
 		}
+		log.Warnf("Join attempt %d failed, retrying in %s: %v", attempt+1, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
 	}
+}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	wg.Wait()
+// loadKeyRing loads encryption keys from file or environment variable
+// returns nil if encryption is not enabled
+func loadKeyRing(file string) *chord.KeyRing {
+	var keyRing *chord.KeyRing
+	var err error
+	if file != "" {
+		keyRing, err = chord.LoadKeyRing(file)
+	} else if definition := os.Getenv(encryptionKeysEnv); definition != "" {
+		keyRing, err = chord.NewKeyRing(definition)
+	}
+	if err != nil {
+		log.Fatalf("Error loading encryption keys: %v", err)
+	}
+	return keyRing
+}
+
+// newNode makes local node, identifier is derived from the public key if identity is set
+// the signed claim is kept in the node, so other nodes accept its identifier
+func newNode(ip string, port uint, identity ed25519.PrivateKey) *chord.Node {
+	if identity != nil {
+		node := chord.NewNodeWithPublicKey(ip, port, identity.Public().(ed25519.PublicKey))
+		net.SignNode(node, identity)
+		return node
+	}
+	return chord.NewNode(ip, port)
 }
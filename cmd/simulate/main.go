@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mbrostami/chord/simulator"
+	log "github.com/sirupsen/logrus"
+)
+
+// simulate runs a ring in memory with churn and reports invariant violations
+func main() {
+	seed := flag.Int64("seed", 1, "random seed, same seed reproduces the same run")
+	nodes := flag.Int("nodes", 20, "number of nodes")
+	keys := flag.Int("keys", 50, "number of stored keys")
+	failures := flag.Int("failures", 2, "number of nodes crashed after the ring is stable")
+	partition := flag.Int("partition", 60, "steps to keep the ring split in halves before healing, 0 disables")
+	steps := flag.Int("steps", 300, "maximum steps to wait for the ring to be stable")
+	verbose := flag.Bool("v", false, "print ring logs")
+	flag.Parse()
+	log.SetLevel(log.FatalLevel)
+	if *verbose {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	sim := simulator.New(*seed)
+	for i := 0; i < *nodes; i++ {
+		if _, err := sim.AddNode(fmt.Sprintf("10.0.%d.%d", i/250, i%250+1), 10001); err != nil {
+			fmt.Printf("join failed: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	report(sim, "join", *steps)
+	for i := 0; i < *keys; i++ {
+		if err := sim.Store(fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i))); err != nil {
+			fmt.Printf("store failed: %v\n", err)
+		}
+	}
+	report(sim, "store", *steps)
+	for i := 0; i < *failures; i++ {
+		node, err := sim.FailRandom()
+		if err != nil {
+			break
+		}
+		fmt.Printf("failed %s\n", node.Address)
+	}
+	if !report(sim, "failure", *steps) {
+		os.Exit(1)
+	}
+	if *partition == 0 {
+		return
+	}
+	first, second := sim.PartitionHalves()
+	fmt.Printf("partitioned %d and %d nodes\n", len(first), len(second))
+	sim.Run(*partition/2, nil)
+	// each side stores keys in its own ring, they must survive the merge
+	for i := 0; i < *keys/2; i++ {
+		if err := sim.Store(fmt.Sprintf("partitioned-key-%d", i), []byte(fmt.Sprintf("value-%d", i))); err != nil {
+			fmt.Printf("store failed: %v\n", err)
+		}
+	}
+	sim.Run(*partition-*partition/2, nil)
+	sim.Heal()
+	if !report(sim, "merge", *steps) {
+		os.Exit(1)
+	}
+}
+
+// report runs until the ring is stable and prints remaining violations
+func report(sim *simulator.Simulator, phase string, steps int) bool {
+	taken, violations := sim.RunUntilStable(steps)
+	if len(violations) == 0 {
+		fmt.Printf("%s: stable after %d steps\n", phase, taken)
+		return true
+	}
+	fmt.Printf("%s: %d violations after %d steps\n", phase, len(violations), taken)
+	for _, violation := range violations {
+		fmt.Printf("  %s\n", violation)
+	}
+	return false
+}
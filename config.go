@@ -0,0 +1,93 @@
+package chord
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// default tunables of the ring
+const (
+	defaultDataDir          string        = "/tmp"
+	defaultLocationCacheTTL time.Duration = 30 * time.Second
+	defaultMemberTTL        time.Duration = 24 * time.Hour
+)
+
+// Config tunables of the ring, zero values are replaced by defaults
+// Replicas and SuccessorListSize must be the same in all nodes of a ring
+type Config struct {
+	// Replicas is the number of nodes keeping each record
+	Replicas int `yaml:"replicas"`
+	// SuccessorListSize is the number of nodes in successor and predecessor lists
+	// ref E.3 - Theorem IV.5
+	SuccessorListSize int `yaml:"successor_list_size"`
+	// PhiThreshold is the suspicion level to consider a node as failed
+	PhiThreshold float64 `yaml:"phi_threshold"`
+	// DataDir is the directory of the node's database
+	DataDir string `yaml:"data_dir"`
+	// LocationCacheTTL is the expiration of cached lookup results
+	LocationCacheTTL time.Duration `yaml:"location_cache_ttl"`
+	// MemberTTL is how long members are remembered after they were last seen
+	// it should be longer than partitions which are expected to heal
+	MemberTTL time.Duration `yaml:"member_ttl"`
+	// RequireSignedRecords rejects records which are not signed by trusted writers in store and replication
+	RequireSignedRecords bool `yaml:"require_signed_records"`
+	// TrustedWriters are the public keys allowed to sign records
+	TrustedWriters WriterKeys `yaml:"-"`
+	// KeyRing encrypts record contents at rest, nil means plain
+	KeyRing *KeyRing `yaml:"-"`
+	// Clock is the source of time, e.g. a virtual clock in simulations, nil means system time
+	Clock Clock `yaml:"-"`
+}
+
+// DefaultConfig returns the default tunables
+func DefaultConfig() Config {
+	return Config{}.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	if c.Replicas == 0 {
+		c.Replicas = REPLICAS
+	}
+	if c.SuccessorListSize == 0 {
+		c.SuccessorListSize = RSIZE
+	}
+	if c.PhiThreshold == 0 {
+		c.PhiThreshold = PHITHRESHOLD
+	}
+	if c.DataDir == "" {
+		c.DataDir = defaultDataDir
+	}
+	if c.LocationCacheTTL == 0 {
+		c.LocationCacheTTL = defaultLocationCacheTTL
+	}
+	if c.MemberTTL == 0 {
+		c.MemberTTL = defaultMemberTTL
+	}
+	if c.Clock == nil {
+		c.Clock = systemClock{}
+	}
+	return c
+}
+
+// Validate checks if tunables are consistent
+func (c Config) Validate() error {
+	// replication syncs ranges of the first predecessors, so at least one predecessor is needed
+	if c.Replicas < 2 {
+		return fmt.Errorf("config: replicas must be at least 2, got %d", c.Replicas)
+	}
+	// responsible range is found by the predecessor list
+	if c.SuccessorListSize < c.Replicas {
+		return fmt.Errorf("config: successor list size %d must be at least replicas %d", c.SuccessorListSize, c.Replicas)
+	}
+	if c.PhiThreshold <= 0 {
+		return errors.New("config: phi threshold must be positive")
+	}
+	if c.LocationCacheTTL < 0 || c.MemberTTL < 0 {
+		return errors.New("config: ttl must not be negative")
+	}
+	if c.RequireSignedRecords && len(c.TrustedWriters) == 0 {
+		return errors.New("config: trusted writers are required to require signed records")
+	}
+	return nil
+}
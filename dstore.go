@@ -2,9 +2,13 @@ package chord
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/mbrostami/chord/helpers"
@@ -15,14 +19,29 @@ import (
 const DBREPLICAS int = 1
 const bucket string = "storage"
 
+// namespacePrefix is the bucket name prefix of namespaces
+const namespacePrefix string = "ns:"
+
+// ACLNamespace is the reserved namespace to keep access control lists of other namespaces
+// so they are replicated alongside the data
+const ACLNamespace string = "_acl"
+
 type DStore struct {
 	database *bolt.DB
 	db       map[[helpers.HashSize]byte]*[]byte
 }
 
-func NewDStore(uniqueID string) *DStore {
-	//uniqueID = string(time.Now().Second())
-	db, err := bolt.Open("/tmp/chord_"+uniqueID, 0600, nil)
+// DStorePath returns the database file of the node in the data directory
+func DStorePath(dataDir string, uniqueID string) string {
+	return filepath.Join(dataDir, "chord_"+uniqueID)
+}
+
+// NewDStore opens the database file, data directory is created if it's missing
+func NewDStore(path string) *DStore {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		log.Fatal(err)
+	}
+	db, err := bolt.Open(path, 0600, nil)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -43,12 +62,45 @@ type Record struct {
 	CreationTime time.Time              `json:"creation_time"`
 	Content      []byte                 `json:"content"`
 	Identifier   [helpers.HashSize]byte `json:"identifier"`
+	Namespace    string                 `json:"namespace,omitempty"`
+	Key          string                 `json:"key,omitempty"`        // original key, identifier is derived from it
+	WriterKey    []byte                 `json:"writer_key,omitempty"` // ed25519 public key of the writer
+	Signature    []byte                 `json:"signature,omitempty"`
+	KeyID        string                 `json:"key_id,omitempty"` // encryption key of the content, empty if it's plain
+	Nonce        []byte                 `json:"nonce,omitempty"`
+	Deleted      bool                   `json:"deleted,omitempty"` // tombstone, kept to replicate the deletion
+}
+
+// NewTombstone makes the deletion record of the key in the namespace
+// tombstones replace the record like a newer write, so replicas don't bring it back
+func NewTombstone(namespace string, key string) *Record {
+	return &Record{
+		CreationTime: time.Now(),
+		Identifier:   RecordIdentifier(namespace, key),
+		Namespace:    namespace,
+		Key:          key,
+		Deleted:      true,
+	}
+}
+
+// RecordIdentifier calculates identifier of the key in the namespace
+// so same keys in different namespaces don't collide
+func RecordIdentifier(namespace string, key string) [helpers.HashSize]byte {
+	if namespace == "" {
+		return helpers.Hash(key)
+	}
+	return helpers.Hash(namespace + "/" + key)
 }
 
 func (r *Record) Hash() [helpers.HashSize]byte {
 	return r.Identifier
 }
 
+// NewerThan check if the record is written after the other one, last writer wins
+func (r *Record) NewerThan(other *Record) bool {
+	return r.CreationTime.After(other.CreationTime)
+}
+
 func (r *Record) GetJson() []byte {
 	json, _ := json.Marshal(r)
 	return json
@@ -74,105 +126,364 @@ func (d *DStore) PutRecord(record Record) bool {
 	json, _ := json.Marshal(record)
 	key := record.Identifier
 	// log.Debugf("storing data %x: %v", key, json)
-	d.database.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucket))
-		err := b.Put(key[:], json)
-		return err
+	err := d.database.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucketName(record.Namespace))
+		if err != nil {
+			return err
+		}
+		return b.Put(key[:], json)
 	})
-	return true
+	return err == nil
 }
 
 func (d *DStore) GetRange(fromKey [helpers.HashSize]byte, toKey [helpers.HashSize]byte) map[[helpers.HashSize]byte]*Record {
 	data := make(map[[helpers.HashSize]byte]*Record)
 	d.database.View(func(tx *bolt.Tx) error {
-		// Assume our events bucket exists and has RFC3339 encoded time keys.
-		c := tx.Bucket([]byte(bucket)).Cursor()
-		// Our time range spans the 90's decade.
-		min := fromKey[:]
-		max := toKey[:]
-		// Iterate over the 90's.
-		for k, value := c.Seek(min); k != nil && bytes.Compare(k, max) <= 0; k, value = c.Next() {
-			var key [helpers.HashSize]byte
-			copy(key[:helpers.HashSize], k[:helpers.HashSize])
-			record := Record{}
-			json.Unmarshal(value, &record)
-			data[key] = &record
-		}
-		return nil
+		return forEachBucket(tx, func(b *bolt.Bucket) {
+			c := b.Cursor()
+			min := fromKey[:]
+			max := toKey[:]
+			for k, value := c.Seek(min); k != nil && bytes.Compare(k, max) <= 0; k, value = c.Next() {
+				var key [helpers.HashSize]byte
+				copy(key[:helpers.HashSize], k[:helpers.HashSize])
+				record := Record{}
+				json.Unmarshal(value, &record)
+				data[key] = &record
+			}
+		})
 	})
 	return data
 }
 
+// GetRangeCircular returns records of all namespaces in range [fromKey, toKey]
+// root hash is calculated over all namespaces in bucket name order
 func (d *DStore) GetRangeCircular(fromKey [helpers.HashSize]byte, toKey [helpers.HashSize]byte) (map[[helpers.HashSize]byte]*Record, [helpers.HashSize]byte) {
 	data := make(map[[helpers.HashSize]byte]*Record)
 	var rootHash [helpers.HashSize]byte
 	d.database.View(func(tx *bolt.Tx) error {
-		// Assume our events bucket exists and has RFC3339 encoded time keys.
-		c := tx.Bucket([]byte(bucket)).Cursor()
-		// Our time range spans the 90's decade.
-		min := fromKey[:]
-		max := toKey[:]
-		// if fromKey is greater than toKey means, we need to connect last hash to first hash,
-		// and get all keys between those,
-		// e.g. a,b,c,d - getRange(c, b) -> should return [d, a]
-		if helpers.GreaterThan(fromKey, toKey) {
-			// return all keys less than max (tokey)
-			for k, value := c.First(); k != nil && bytes.Compare(k, max) <= 0; k, value = c.Next() {
-				var key [helpers.HashSize]byte
-				copy(key[:helpers.HashSize], k[:helpers.HashSize])
-				record := Record{}
-				json.Unmarshal(value, &record)
-				rootHash = helpers.Hash(string(append(rootHash[:], record.Identifier[:]...)))
-				data[key] = &record
+		return forEachBucket(tx, func(b *bolt.Bucket) {
+			c := b.Cursor()
+			min := fromKey[:]
+			max := toKey[:]
+			// if fromKey is greater than toKey means, we need to connect last hash to first hash,
+			// and get all keys between those,
+			// e.g. a,b,c,d - getRange(c, b) -> should return [d, a]
+			if helpers.GreaterThan(fromKey, toKey) {
+				// return all keys less than max (tokey)
+				for k, value := c.First(); k != nil && bytes.Compare(k, max) <= 0; k, value = c.Next() {
+					var key [helpers.HashSize]byte
+					copy(key[:helpers.HashSize], k[:helpers.HashSize])
+					record := Record{}
+					json.Unmarshal(value, &record)
+					rootHash = chainHash(rootHash, &record)
+					data[key] = &record
+				}
+				// return all keys greater than min (fromKey)
+				for k, value := c.Seek(min); k != nil && bytes.Compare(k, min) >= 0; k, value = c.Next() {
+					var key [helpers.HashSize]byte
+					copy(key[:helpers.HashSize], k[:helpers.HashSize])
+					record := Record{}
+					json.Unmarshal(value, &record)
+					rootHash = chainHash(rootHash, &record)
+					data[key] = &record
+				}
+			} else {
+				for k, value := c.Seek(min); k != nil && bytes.Compare(k, max) <= 0; k, value = c.Next() {
+					var key [helpers.HashSize]byte
+					copy(key[:helpers.HashSize], k[:helpers.HashSize])
+					record := Record{}
+					json.Unmarshal(value, &record)
+					rootHash = chainHash(rootHash, &record)
+					data[key] = &record
+				}
 			}
-			// return all keys greater than min (fromKey)
-			for k, value := c.Seek(min); k != nil && bytes.Compare(k, min) >= 0; k, value = c.Next() {
+		})
+	})
+	return data, rootHash
+}
+
+func (d *DStore) Get(namespace string, key [helpers.HashSize]byte) []byte {
+	var result []byte
+	d.database.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName(namespace))
+		if b == nil {
+			return nil
+		}
+		result = b.Get(key[:])
+		return nil
+	})
+	return result
+}
+
+func (d *DStore) GetAll() map[[helpers.HashSize]byte]*Record {
+	data := make(map[[helpers.HashSize]byte]*Record)
+	d.database.View(func(tx *bolt.Tx) error {
+		return forEachBucket(tx, func(b *bolt.Bucket) {
+			c := b.Cursor()
+			for k, value := c.First(); k != nil; k, value = c.Next() {
 				var key [helpers.HashSize]byte
 				copy(key[:helpers.HashSize], k[:helpers.HashSize])
 				record := Record{}
 				json.Unmarshal(value, &record)
-				rootHash = helpers.Hash(string(append(rootHash[:], record.Identifier[:]...)))
 				data[key] = &record
 			}
-		} else {
-			for k, value := c.Seek(min); k != nil && bytes.Compare(k, max) <= 0; k, value = c.Next() {
-				var key [helpers.HashSize]byte
-				copy(key[:helpers.HashSize], k[:helpers.HashSize])
+		})
+	})
+	return data
+}
+
+// UpdateRecords calls fn for records of all namespaces in bucket name and key order, starting after the cursor
+// records changed by fn are written in the same transaction, so writes of other goroutines are not overwritten
+// at most limit records are changed, returns the cursor of the last visited record or nil if all records are visited
+func (d *DStore) UpdateRecords(after *RangeCursor, limit int, fn func(record *Record) bool) *RangeCursor {
+	var next *RangeCursor
+	d.database.Update(func(tx *bolt.Tx) error {
+		changed := 0
+		err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if string(name) != bucket && !bytes.HasPrefix(name, []byte(namespacePrefix)) {
+				return nil
+			}
+			c := b.Cursor()
+			k, value := c.First()
+			if after != nil {
+				switch bytes.Compare(name, bucketName(after.Namespace)) {
+				case -1: // already visited
+					return nil
+				case 0:
+					k, value = c.Seek(after.Key[:])
+					if k != nil && bytes.Equal(k, after.Key[:]) {
+						k, value = c.Next()
+					}
+				}
+			}
+			// cursor is invalidated by writes, so changed records are written after the iteration
+			updates := []*Record{}
+			for ; k != nil; k, value = c.Next() {
+				record := &Record{}
+				json.Unmarshal(value, record)
+				if fn(record) {
+					updates = append(updates, record)
+				}
+				if changed+len(updates) >= limit {
+					next = &RangeCursor{Namespace: record.Namespace}
+					copy(next.Key[:], k)
+					break
+				}
+			}
+			for _, record := range updates {
+				value, _ := json.Marshal(record)
+				if err := b.Put(record.Identifier[:], value); err != nil {
+					return err
+				}
+			}
+			changed += len(updates)
+			if next != nil {
+				return errBatchFull
+			}
+			return nil
+		})
+		if err == errBatchFull {
+			return nil
+		}
+		return err
+	})
+	return next
+}
+
+// RangeHash returns the root hash of records ∈ (fromKey, toKey] of all namespaces
+// records are hashed in ScanRange order while they are read, without keeping the range in memory
+func (d *DStore) RangeHash(fromKey [helpers.HashSize]byte, toKey [helpers.HashSize]byte) [helpers.HashSize]byte {
+	var rootHash [helpers.HashSize]byte
+	d.database.View(func(tx *bolt.Tx) error {
+		return forEachBucket(tx, func(b *bolt.Bucket) {
+			scanCircular(b.Cursor(), fromKey, toKey, func(value []byte) bool {
 				record := Record{}
 				json.Unmarshal(value, &record)
-				rootHash = helpers.Hash(string(append(rootHash[:], record.Identifier[:]...)))
-				data[key] = &record
-			}
+				rootHash = chainHash(rootHash, &record)
+				return true
+			})
+		})
+	})
+	return rootHash
+}
+
+// rangeIterator reads records ∈ (from, to] in ScanRange order, a batch at a time
+type rangeIterator struct {
+	dstore *DStore
+	from   [helpers.HashSize]byte
+	to     [helpers.HashSize]byte
+	after  *RangeCursor
+	batch  []*Record
+	done   bool
+}
+
+func newRangeIterator(dstore *DStore, from [helpers.HashSize]byte, to [helpers.HashSize]byte) *rangeIterator {
+	return &rangeIterator{dstore: dstore, from: from, to: to}
+}
+
+// Peek returns the current record, nil if the range is finished
+func (it *rangeIterator) Peek() *Record {
+	if len(it.batch) == 0 && !it.done {
+		it.batch = it.dstore.ScanRange(it.from, it.to, it.after, transferBatch, transferBatchBytes)
+		if len(it.batch) == 0 {
+			it.done = true
+			return nil
 		}
+		last := it.batch[len(it.batch)-1]
+		it.after = &RangeCursor{Namespace: last.Namespace, Key: last.Identifier}
+	}
+	if len(it.batch) == 0 {
 		return nil
+	}
+	return it.batch[0]
+}
+
+// Next moves to the next record
+func (it *rangeIterator) Next() {
+	if len(it.batch) > 0 {
+		it.batch = it.batch[1:]
+	}
+}
+
+// rangeLess check if record a comes before record b in ScanRange order of (from, ...]
+// namespaces are in bucket name order, keys after from come before the wrapped keys
+func rangeLess(a *Record, b *Record, from [helpers.HashSize]byte) bool {
+	if order := bytes.Compare(bucketName(a.Namespace), bucketName(b.Namespace)); order != 0 {
+		return order < 0
+	}
+	aWrapped := bytes.Compare(a.Identifier[:], from[:]) <= 0
+	bWrapped := bytes.Compare(b.Identifier[:], from[:]) <= 0
+	if aWrapped != bWrapped {
+		return bWrapped
+	}
+	return bytes.Compare(a.Identifier[:], b.Identifier[:]) < 0
+}
+
+// RangeCursor is the position of the last transferred record in a range scan
+type RangeCursor struct {
+	Namespace string
+	Key       [helpers.HashSize]byte
+}
+
+// ScanRange returns records ∈ (fromKey, toKey] of all namespaces, in bucket name and ring order
+// starting after the cursor if it's given, so a transfer can be resumed
+// at most limit records are returned and the batch is cut after maxBytes of content
+func (d *DStore) ScanRange(fromKey [helpers.HashSize]byte, toKey [helpers.HashSize]byte, after *RangeCursor, limit int, maxBytes int) []*Record {
+	records := []*Record{}
+	size := 0
+	d.database.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if string(name) != bucket && !bytes.HasPrefix(name, []byte(namespacePrefix)) {
+				return nil
+			}
+			start := fromKey
+			if after != nil {
+				switch bytes.Compare(name, bucketName(after.Namespace)) {
+				case -1: // already transferred
+					return nil
+				case 0:
+					if after.Key == toKey { // namespace is completed
+						return nil
+					}
+					start = after.Key
+				}
+			}
+			full := scanCircular(b.Cursor(), start, toKey, func(value []byte) bool {
+				record := &Record{}
+				json.Unmarshal(value, record)
+				records = append(records, record)
+				size += len(record.Content)
+				return len(records) < limit && size < maxBytes
+			})
+			if full {
+				return errBatchFull
+			}
+			return nil
+		})
 	})
-	return data, rootHash
+	return records
 }
 
-func (d *DStore) Get(key [helpers.HashSize]byte) []byte {
-	var result []byte
+// ScanNamespace returns records ∈ (fromKey, toKey] of the namespace in ring order
+// at most limit records are returned and the batch is cut after maxBytes of content
+func (d *DStore) ScanNamespace(namespace string, fromKey [helpers.HashSize]byte, toKey [helpers.HashSize]byte, limit int, maxBytes int) []*Record {
+	records := []*Record{}
+	size := 0
 	d.database.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucket))
-		result = b.Get(key[:])
+		b := tx.Bucket(bucketName(namespace))
+		if b == nil {
+			return nil
+		}
+		scanCircular(b.Cursor(), fromKey, toKey, func(value []byte) bool {
+			record := &Record{}
+			json.Unmarshal(value, record)
+			records = append(records, record)
+			size += len(record.Content)
+			return len(records) < limit && size < maxBytes
+		})
 		return nil
 	})
-	return result
+	return records
 }
 
-func (d *DStore) GetAll() map[[helpers.HashSize]byte]*Record {
-	data := make(map[[helpers.HashSize]byte]*Record)
-	d.database.View(func(tx *bolt.Tx) error {
-		// Assume bucket exists and has keys
-		b := tx.Bucket([]byte(bucket))
-		c := b.Cursor()
-		for k, value := c.First(); k != nil; k, value = c.Next() {
-			var key [helpers.HashSize]byte
-			copy(key[:helpers.HashSize], k[:helpers.HashSize])
-			record := Record{}
-			json.Unmarshal(value, &record)
-			data[key] = &record
+// errBatchFull stops iterating buckets
+var errBatchFull = errors.New("batch is full")
+
+// scanCircular calls fn for values of keys ∈ (fromKey, toKey] until fn returns false
+// range wraps around if fromKey is not less than toKey
+// returns true if it's stopped by fn
+func scanCircular(c *bolt.Cursor, fromKey [helpers.HashSize]byte, toKey [helpers.HashSize]byte, fn func(value []byte) bool) bool {
+	min := fromKey[:]
+	max := toKey[:]
+	k, value := c.Seek(min)
+	if k != nil && bytes.Equal(k, min) {
+		k, value = c.Next()
+	}
+	if bytes.Compare(min, max) < 0 {
+		for ; k != nil && bytes.Compare(k, max) <= 0; k, value = c.Next() {
+			if !fn(value) {
+				return true
+			}
+		}
+		return false
+	}
+	// e.g. a,b,c,d - scan(c, b] -> d, a, b
+	for ; k != nil; k, value = c.Next() {
+		if !fn(value) {
+			return true
+		}
+	}
+	for k, value = c.First(); k != nil && bytes.Compare(k, max) <= 0; k, value = c.Next() {
+		if !fn(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// chainHash adds the record to the root hash
+// creation time is included, so updated and deleted records change the root hash too
+func chainHash(rootHash [helpers.HashSize]byte, record *Record) [helpers.HashSize]byte {
+	version := make([]byte, 8)
+	binary.BigEndian.PutUint64(version, uint64(record.CreationTime.UnixNano()))
+	chain := append(rootHash[:], record.Identifier[:]...)
+	return helpers.Hash(string(append(chain, version...)))
+}
+
+// bucketName returns the bucket of the namespace, default namespace is stored in storage bucket
+func bucketName(namespace string) []byte {
+	if namespace == "" {
+		return []byte(bucket)
+	}
+	return []byte(namespacePrefix + namespace)
+}
+
+// forEachBucket calls fn for storage bucket and all namespace buckets in name order
+func forEachBucket(tx *bolt.Tx, fn func(b *bolt.Bucket)) error {
+	return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if string(name) == bucket || bytes.HasPrefix(name, []byte(namespacePrefix)) {
+			fn(b)
 		}
 		return nil
 	})
-	return data
 }
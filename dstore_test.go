@@ -0,0 +1,196 @@
+package chord
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mbrostami/chord/helpers"
+)
+
+// newTestDStore opens a store in a temporary directory, removed when the test is done
+func newTestDStore(t *testing.T) *DStore {
+	dir, err := ioutil.TempDir("", "chord-dstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return NewDStore(filepath.Join(dir, "chord_test"))
+}
+
+// storedRecord reads the record of the key from the store
+func storedRecord(t *testing.T, d *DStore, namespace string, key string) *Record {
+	data := d.Get(namespace, RecordIdentifier(namespace, key))
+	if data == nil {
+		t.Fatalf("record %s/%s is not stored", namespace, key)
+	}
+	record := &Record{}
+	if err := json.Unmarshal(data, record); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func TestUpdateRecordsVisitsAllNamespacesInBatches(t *testing.T) {
+	d := newTestDStore(t)
+	keys := map[string][]string{"": {"a", "b"}, "x": {"c", "d", "e"}}
+	for namespace, names := range keys {
+		for _, key := range names {
+			d.PutRecord(*testRecord(namespace, key, "plain"))
+		}
+	}
+	var cursor *RangeCursor
+	batches := 0
+	visited := map[string]int{}
+	for {
+		cursor = d.UpdateRecords(cursor, 2, func(record *Record) bool {
+			visited[record.Namespace+"/"+record.Key]++
+			record.Content = []byte("updated")
+			return true
+		})
+		batches++
+		if cursor == nil {
+			break
+		}
+		if batches > 5 {
+			t.Fatalf("update doesn't finish")
+		}
+	}
+	if batches != 3 {
+		t.Errorf("5 records are updated in %d batches of 2, expected 3", batches)
+	}
+	for namespace, names := range keys {
+		for _, key := range names {
+			if count := visited[namespace+"/"+key]; count != 1 {
+				t.Errorf("%s/%s is visited %d times", namespace, key, count)
+			}
+			if content := string(storedRecord(t, d, namespace, key).Content); content != "updated" {
+				t.Errorf("%s/%s content is %q after the update", namespace, key, content)
+			}
+		}
+	}
+}
+
+func TestUpdateRecordsKeepsUnchangedRecords(t *testing.T) {
+	d := newTestDStore(t)
+	d.PutRecord(*testRecord("x", "a", "plain"))
+	d.PutRecord(*testRecord("x", "b", "plain"))
+	cursor := d.UpdateRecords(nil, 10, func(record *Record) bool {
+		record.Content = []byte("discarded")
+		return record.Key == "b"
+	})
+	if cursor != nil {
+		t.Fatalf("cursor %v is returned after all records are visited", cursor)
+	}
+	if content := string(storedRecord(t, d, "x", "a").Content); content != "plain" {
+		t.Errorf("record which is not changed by fn is written, content %q", content)
+	}
+	if content := string(storedRecord(t, d, "x", "b").Content); content != "discarded" {
+		t.Errorf("changed record is not written, content %q", content)
+	}
+}
+
+func TestUpdateRecordsResumesAfterCursor(t *testing.T) {
+	d := newTestDStore(t)
+	d.PutRecord(*testRecord("x", "a", "plain"))
+	d.PutRecord(*testRecord("x", "b", "plain"))
+	first := d.UpdateRecords(nil, 1, func(record *Record) bool { return true })
+	if first == nil {
+		t.Fatalf("cursor is not returned when the batch is full")
+	}
+	visited := 0
+	d.UpdateRecords(first, 10, func(record *Record) bool {
+		visited++
+		if record.Identifier == first.Key {
+			t.Errorf("record of the cursor is visited again")
+		}
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("%d records are visited after the cursor, expected 1", visited)
+	}
+}
+
+// sortedRecords returns records of the keys in the namespace, ordered by identifier
+func sortedRecords(namespace string, keys ...string) []*Record {
+	records := make([]*Record, 0, len(keys))
+	for _, key := range keys {
+		record := testRecord(namespace, key, key)
+		record.CreationTime = time.Unix(1, 0)
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return bytes.Compare(records[i].Identifier[:], records[j].Identifier[:]) < 0
+	})
+	return records
+}
+
+func TestRangeHashMatchesScanRange(t *testing.T) {
+	d := newTestDStore(t)
+	records := append(sortedRecords("", "a", "b", "c", "d"), sortedRecords("x", "e", "f", "g")...)
+	for _, record := range records {
+		d.PutRecord(*record)
+	}
+	from, to := records[0].Identifier, records[2].Identifier
+	var expected [helpers.HashSize]byte
+	for _, record := range d.ScanRange(from, to, nil, len(records), 1<<20) {
+		expected = chainHash(expected, record)
+	}
+	if expected == ([helpers.HashSize]byte{}) {
+		t.Fatalf("range is empty")
+	}
+	if rootHash := d.RangeHash(from, to); rootHash != expected {
+		t.Errorf("range hash is %x, expected %x", rootHash, expected)
+	}
+	// circular range wraps around the identifier space
+	expected = [helpers.HashSize]byte{}
+	for _, record := range d.ScanRange(to, from, nil, len(records), 1<<20) {
+		expected = chainHash(expected, record)
+	}
+	if rootHash := d.RangeHash(to, from); rootHash != expected {
+		t.Errorf("circular range hash is %x, expected %x", rootHash, expected)
+	}
+}
+
+func TestRangeHashOfSameRecordsIsEqual(t *testing.T) {
+	records := sortedRecords("x", "a", "b", "c", "d")
+	d1 := newTestDStore(t)
+	d2 := newTestDStore(t)
+	for i := range records {
+		d1.PutRecord(*records[i])
+		d2.PutRecord(*records[len(records)-1-i])
+	}
+	from, to := records[0].Identifier, records[2].Identifier
+	if d1.RangeHash(from, to) != d2.RangeHash(from, to) {
+		t.Errorf("range hashes of same records written in different orders are different")
+	}
+}
+
+func TestRangeHashChangesOnlyWithRecordsOfRange(t *testing.T) {
+	d := newTestDStore(t)
+	records := sortedRecords("x", "a", "b", "c", "d")
+	for _, record := range records {
+		d.PutRecord(*record)
+	}
+	from, to := records[0].Identifier, records[2].Identifier
+	rootHash := d.RangeHash(from, to)
+
+	outside := *records[3]
+	outside.CreationTime = time.Unix(2, 0)
+	d.PutRecord(outside)
+	if d.RangeHash(from, to) != rootHash {
+		t.Errorf("range hash is changed by a record out of the range")
+	}
+
+	updated := *records[1]
+	updated.CreationTime = time.Unix(2, 0)
+	d.PutRecord(updated)
+	if d.RangeHash(from, to) == rootHash {
+		t.Errorf("range hash is not changed by an updated record")
+	}
+}
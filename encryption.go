@@ -0,0 +1,186 @@
+package chord
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned when the record is encrypted by a key which is not in the key ring
+var ErrUnknownKey = errors.New("encryption key is unknown")
+
+// ErrEncryptedRecord is returned when the client stores a record with encryption fields
+// records are encrypted only by the nodes, so clients can't choose the key or the nonce
+var ErrEncryptedRecord = errors.New("record must not be encrypted by the client")
+
+// KeyRing keeps AES keys to encrypt record contents at rest
+// the active key encrypts new records, old keys are kept to decrypt old records
+// keys are defined as "id:base64key" separated by comma or new line, the first one is active
+type KeyRing struct {
+	mutex   sync.RWMutex
+	keys    map[string]cipher.AEAD
+	active  string
+	file    string
+	modTime time.Time
+}
+
+// NewKeyRing parses keys definition
+func NewKeyRing(definition string) (*KeyRing, error) {
+	keyRing := &KeyRing{}
+	if err := keyRing.parse(definition); err != nil {
+		return nil, err
+	}
+	return keyRing, nil
+}
+
+// LoadKeyRing loads keys definition from file, file can be reloaded to rotate keys
+func LoadKeyRing(file string) (*KeyRing, error) {
+	keyRing := &KeyRing{file: file}
+	if err := keyRing.Reload(); err != nil {
+		return nil, err
+	}
+	return keyRing, nil
+}
+
+// Reload reloads keys from file if it's changed
+func (k *KeyRing) Reload() error {
+	if k.file == "" {
+		return nil
+	}
+	info, err := os.Stat(k.file)
+	if err != nil {
+		return err
+	}
+	k.mutex.RLock()
+	changed := info.ModTime().After(k.modTime)
+	k.mutex.RUnlock()
+	if !changed {
+		return nil
+	}
+	definition, err := ioutil.ReadFile(k.file)
+	if err != nil {
+		return err
+	}
+	if err := k.parse(string(definition)); err != nil {
+		return err
+	}
+	k.mutex.Lock()
+	k.modTime = info.ModTime()
+	k.mutex.Unlock()
+	return nil
+}
+
+func (k *KeyRing) parse(definition string) error {
+	keys := make(map[string]cipher.AEAD)
+	active := ""
+	entries := strings.FieldsFunc(definition, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return errors.New("encryption key must be defined as id:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		keys[parts[0]] = aead
+		if active == "" {
+			active = parts[0]
+		}
+	}
+	if active == "" {
+		return errors.New("no encryption key is defined")
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keys = keys
+	k.active = active
+	return nil
+}
+
+// ActiveKeyID returns id of the key which encrypts new records
+func (k *KeyRing) ActiveKeyID() string {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.active
+}
+
+// HasKey check if the key is in the key ring
+func (k *KeyRing) HasKey(keyID string) bool {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	_, found := k.keys[keyID]
+	return found
+}
+
+// Encrypt encrypts record content by the active key
+// identifier and namespace are authenticated, so ciphertext can't be moved to another record
+func (k *KeyRing) Encrypt(record *Record) error {
+	if record.IsEncrypted() {
+		return nil
+	}
+	k.mutex.RLock()
+	keyID := k.active
+	aead := k.keys[keyID]
+	k.mutex.RUnlock()
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	record.Content = aead.Seal(nil, nonce, record.Content, record.additionalData())
+	record.Nonce = nonce
+	record.KeyID = keyID
+	return nil
+}
+
+// Decrypt decrypts record content
+func (k *KeyRing) Decrypt(record *Record) error {
+	if !record.IsEncrypted() {
+		return nil
+	}
+	k.mutex.RLock()
+	aead, found := k.keys[record.KeyID]
+	k.mutex.RUnlock()
+	if !found {
+		return ErrUnknownKey
+	}
+	content, err := aead.Open(nil, record.Nonce, record.Content, record.additionalData())
+	if err != nil {
+		return err
+	}
+	record.Content = content
+	record.Nonce = nil
+	record.KeyID = ""
+	return nil
+}
+
+// IsEncrypted check if record content is encrypted
+func (r *Record) IsEncrypted() bool {
+	return r.KeyID != ""
+}
+
+func (r *Record) additionalData() []byte {
+	return append(r.Identifier[:], []byte(r.Namespace)...)
+}
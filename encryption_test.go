@@ -0,0 +1,170 @@
+package chord
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testKey returns an AES-256 key definition whose bytes are all b
+func testKey(id string, b byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func testRecord(namespace string, key string, content string) *Record {
+	return &Record{
+		Namespace:  namespace,
+		Key:        key,
+		Identifier: RecordIdentifier(namespace, key),
+		Content:    []byte(content),
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	keyRing, err := NewKeyRing(testKey("k1", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := testRecord("test", "key", "secret")
+	if err := keyRing.Encrypt(record); err != nil {
+		t.Fatal(err)
+	}
+	if !record.IsEncrypted() || record.KeyID != "k1" {
+		t.Fatalf("record is not encrypted by the active key, key id %q", record.KeyID)
+	}
+	if bytes.Contains(record.Content, []byte("secret")) {
+		t.Fatalf("encrypted content contains the plaintext")
+	}
+	if err := keyRing.Decrypt(record); err != nil {
+		t.Fatal(err)
+	}
+	if string(record.Content) != "secret" || record.IsEncrypted() || record.Nonce != nil {
+		t.Errorf("decrypted record is %+v", record)
+	}
+}
+
+func TestDecryptRejectsMovedCiphertext(t *testing.T) {
+	keyRing, err := NewKeyRing(testKey("k1", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := testRecord("test", "key", "secret")
+	if err := keyRing.Encrypt(record); err != nil {
+		t.Fatal(err)
+	}
+	moved := *record
+	moved.Identifier = RecordIdentifier("test", "other")
+	if err := keyRing.Decrypt(&moved); err == nil {
+		t.Errorf("ciphertext of another record is decrypted")
+	}
+	moved = *record
+	moved.Namespace = "other"
+	if err := keyRing.Decrypt(&moved); err == nil {
+		t.Errorf("ciphertext of another namespace is decrypted")
+	}
+}
+
+func TestInvalidKeyDefinitions(t *testing.T) {
+	for _, definition := range []string{
+		"",
+		"k1",
+		"k1:not-base64!",
+		"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+	} {
+		if _, err := NewKeyRing(definition); err == nil {
+			t.Errorf("key definition %q is accepted", definition)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chord-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "keys")
+	writeKeys := func(definition string, modTime time.Time) {
+		if err := ioutil.WriteFile(file, []byte(definition), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	writeKeys(testKey("k1", 1), now)
+	keyRing, err := LoadKeyRing(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := testRecord("test", "old", "old secret")
+	if err := keyRing.Encrypt(old); err != nil {
+		t.Fatal(err)
+	}
+
+	// new key is active, old one is kept to decrypt old records
+	writeKeys(testKey("k2", 2)+"\n"+testKey("k1", 1), now.Add(time.Minute))
+	if err := keyRing.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if keyRing.ActiveKeyID() != "k2" {
+		t.Fatalf("active key is %q after rotation, expected k2", keyRing.ActiveKeyID())
+	}
+	fresh := testRecord("test", "new", "new secret")
+	if err := keyRing.Encrypt(fresh); err != nil {
+		t.Fatal(err)
+	}
+	if fresh.KeyID != "k2" {
+		t.Errorf("new record is encrypted by %q, expected k2", fresh.KeyID)
+	}
+	decrypted := *old
+	if err := keyRing.Decrypt(&decrypted); err != nil || string(decrypted.Content) != "old secret" {
+		t.Fatalf("old record is not decrypted after rotation: %v", err)
+	}
+
+	// records of removed keys can't be decrypted
+	writeKeys(testKey("k2", 2), now.Add(2*time.Minute))
+	if err := keyRing.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if keyRing.HasKey("k1") {
+		t.Fatalf("removed key is kept")
+	}
+	if err := keyRing.Decrypt(old); err != ErrUnknownKey {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestReloadKeepsKeysOfInvalidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chord-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "keys")
+	if err := ioutil.WriteFile(file, []byte(testKey("k1", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyRing, err := LoadKeyRing(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyRing.Reload(); err == nil {
+		t.Fatalf("invalid key file is loaded")
+	}
+	if keyRing.ActiveKeyID() != "k1" {
+		t.Errorf("active key is %q after a failed reload, expected k1", keyRing.ActiveKeyID())
+	}
+}
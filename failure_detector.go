@@ -0,0 +1,159 @@
+package chord
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mbrostami/chord/helpers"
+)
+
+// PHITHRESHOLD is the default suspicion level to consider a node as failed
+// phi = 8 means the chance of mistake is about 10^-8
+// ref The φ Accrual Failure Detector
+const PHITHRESHOLD float64 = 8
+
+// heartbeatWindow is the number of intervals kept for each node
+const heartbeatWindow int = 100
+
+// firstHeartbeatEstimate is the expected interval when there is not enough history
+const firstHeartbeatEstimate time.Duration = time.Second
+
+// minStdDeviation prevents too sensitive detection when intervals are too regular
+const minStdDeviation time.Duration = 100 * time.Millisecond
+
+// FailureDetector phi accrual failure detector
+// keeps heartbeat intervals of each node and calculates the suspicion level (phi)
+// based on the time passed since the last heartbeat
+type FailureDetector struct {
+	mutex     sync.RWMutex
+	threshold float64
+	history   map[[helpers.HashSize]byte]*heartbeatHistory
+	clock     Clock
+}
+
+type heartbeatHistory struct {
+	last      time.Time
+	intervals []float64 // milliseconds
+}
+
+// NewFailureDetector make new failure detector
+func NewFailureDetector(threshold float64) *FailureDetector {
+	return &FailureDetector{
+		threshold: threshold,
+		history:   make(map[[helpers.HashSize]byte]*heartbeatHistory),
+		clock:     systemClock{},
+	}
+}
+
+// SetClock replaces the source of heartbeat times
+func (f *FailureDetector) SetClock(clock Clock) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.clock = clock
+}
+
+// SetThreshold changes the suspicion level to consider a node as failed
+func (f *FailureDetector) SetThreshold(threshold float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.threshold = threshold
+}
+
+// Heartbeat records a successful response from the node
+func (f *FailureDetector) Heartbeat(identifier [helpers.HashSize]byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := f.clock.Now()
+	history, found := f.history[identifier]
+	if !found {
+		f.history[identifier] = &heartbeatHistory{last: now}
+		return
+	}
+	history.intervals = append(history.intervals, float64(now.Sub(history.last))/float64(time.Millisecond))
+	if len(history.intervals) > heartbeatWindow {
+		history.intervals = history.intervals[1:]
+	}
+	history.last = now
+}
+
+// Track starts tracking a node which has no heartbeat yet
+// e.g. new successor, so it will be suspected if it never responds
+func (f *FailureDetector) Track(identifier [helpers.HashSize]byte) {
+	f.mutex.RLock()
+	_, found := f.history[identifier]
+	f.mutex.RUnlock()
+	if !found {
+		f.Heartbeat(identifier)
+	}
+}
+
+// Forget removes node's history
+func (f *FailureDetector) Forget(identifier [helpers.HashSize]byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.history, identifier)
+}
+
+// Tracked returns identifiers of the nodes which have history
+func (f *FailureDetector) Tracked() [][helpers.HashSize]byte {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	identifiers := make([][helpers.HashSize]byte, 0, len(f.history))
+	for identifier := range f.history {
+		identifiers = append(identifiers, identifier)
+	}
+	return identifiers
+}
+
+// Phi returns the suspicion level of the node, 0 if node is not tracked
+func (f *FailureDetector) Phi(identifier [helpers.HashSize]byte) float64 {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	history, found := f.history[identifier]
+	if !found {
+		return 0
+	}
+	mean, stdDeviation := history.distribution()
+	elapsed := float64(f.clock.Now().Sub(history.last)) / float64(time.Millisecond)
+	return phi(elapsed, mean, stdDeviation)
+}
+
+// Suspected check if the suspicion level of the node is above the threshold
+func (f *FailureDetector) Suspected(identifier [helpers.HashSize]byte) bool {
+	f.mutex.RLock()
+	threshold := f.threshold
+	f.mutex.RUnlock()
+	return f.Phi(identifier) > threshold
+}
+
+// distribution returns mean and standard deviation of intervals in milliseconds
+func (h *heartbeatHistory) distribution() (float64, float64) {
+	minStd := float64(minStdDeviation) / float64(time.Millisecond)
+	if len(h.intervals) == 0 {
+		mean := float64(firstHeartbeatEstimate) / float64(time.Millisecond)
+		return mean, math.Max(mean/4, minStd)
+	}
+	var sum float64
+	for _, interval := range h.intervals {
+		sum += interval
+	}
+	mean := sum / float64(len(h.intervals))
+	var variance float64
+	for _, interval := range h.intervals {
+		variance += (interval - mean) * (interval - mean)
+	}
+	variance = variance / float64(len(h.intervals))
+	return mean, math.Max(math.Sqrt(variance), minStd)
+}
+
+// phi calculates -log10(1 - F(elapsed)) where F is the normal cumulative distribution
+// using the logistic approximation of the cumulative distribution
+func phi(elapsed float64, mean float64, stdDeviation float64) float64 {
+	y := (elapsed - mean) / stdDeviation
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if elapsed > mean {
+		return -math.Log10(e / (1.0 + e))
+	}
+	return -math.Log10(1.0 - 1.0/(1.0+e))
+}
@@ -0,0 +1,103 @@
+package fault
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Injector decides faults of rpcs by its rules
+// rules can be replaced at runtime, e.g. by the admin rpc
+type Injector struct {
+	mutex  sync.Mutex
+	rules  []Rule
+	random *rand.Rand
+	sleep  func(time.Duration)
+}
+
+// NewInjector make new injector, seed makes probabilistic faults reproducible
+func NewInjector(rules []Rule, seed int64) (*Injector, error) {
+	injector := &Injector{
+		random: rand.New(rand.NewSource(seed)),
+		sleep:  time.Sleep,
+	}
+	if err := injector.SetRules(rules); err != nil {
+		return nil, err
+	}
+	return injector, nil
+}
+
+// SetRules replaces all rules, empty rules disable injection
+func (i *Injector) SetRules(rules []Rule) error {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.rules = append([]Rule{}, rules...)
+	log.Infof("fault: %d rules are active", len(rules))
+	return nil
+}
+
+// Rules returns active rules
+func (i *Injector) Rules() []Rule {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return append([]Rule{}, i.rules...)
+}
+
+// SetSleep replaces the function used to delay rpcs, e.g. by a virtual clock
+func (i *Injector) SetSleep(sleep func(time.Duration)) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.sleep = sleep
+}
+
+// inject applies all matching rules in order
+// delays are slept before returning, returns error if the rpc must fail
+// and duplicate if the rpc must be delivered twice
+func (i *Injector) inject(direction Direction, method string, peer string) (duplicate bool, err error) {
+	var delay time.Duration
+	i.mutex.Lock()
+	sleep := i.sleep
+	for _, rule := range i.rules {
+		if !rule.matches(direction, method, peer) {
+			continue
+		}
+		if rule.Probability > 0 && i.random.Float64() >= rule.Probability {
+			continue
+		}
+		switch rule.Action {
+		case ActionDelay:
+			delay += time.Duration(rule.Delay)
+		case ActionDuplicate:
+			duplicate = true
+		case ActionDrop:
+			err = status.Errorf(codes.Unavailable, "fault: %s %s dropped", method, peer)
+		case ActionError:
+			code := rule.Code
+			if code == codes.OK {
+				code = codes.Unavailable
+			}
+			err = status.Errorf(code, "fault: %s %s failed", method, peer)
+		}
+		if err != nil {
+			break
+		}
+	}
+	i.mutex.Unlock()
+	if delay > 0 {
+		log.Debugf("fault: %s %s %s delayed %s", direction, method, peer, delay)
+		sleep(delay)
+	}
+	if err != nil {
+		log.Debugf("fault: %s %s %v", direction, method, err)
+	}
+	return duplicate, err
+}
@@ -0,0 +1,48 @@
+package fault
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryServerInterceptor injects faults into received rpcs
+// rpcs are matched by method name and the caller's address
+// duplicate runs the handler twice and returns the second response
+func (i *Injector) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		duplicate, err := i.inject(DirectionIncoming, methodName(info.FullMethod), callerAddress(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if duplicate {
+			handler(ctx, req)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor injects faults into received streaming rpcs
+// duplicate is ignored, a stream can't be replayed on the same connection
+func (i *Injector) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, err := i.inject(DirectionIncoming, methodName(info.FullMethod), callerAddress(stream.Context())); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// methodName returns rpc name of the full method, e.g. /grpc.Chord/Notify -> Notify
+func methodName(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+func callerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
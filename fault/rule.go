@@ -0,0 +1,125 @@
+package fault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Action is the fault injected into matching rpcs
+type Action string
+
+const (
+	ActionDelay     Action = "delay"     // rpc is delayed by Delay
+	ActionDrop      Action = "drop"      // rpc fails as unavailable, like a lost message
+	ActionDuplicate Action = "duplicate" // rpc is delivered twice
+	ActionError     Action = "error"     // rpc fails with Code
+)
+
+// Direction of the rpcs a rule applies to
+type Direction string
+
+const (
+	DirectionAny      Direction = ""
+	DirectionOutgoing Direction = "outgoing" // rpcs sent by the local node, peer is the remote node
+	DirectionIncoming Direction = "incoming" // rpcs received by the local node, peer is the caller
+)
+
+// Duration is a time.Duration encoded as string in json, e.g. "200ms"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Rule injects the action into rpcs matching method, peer and direction
+// empty method, peer or direction matches all
+type Rule struct {
+	Method    string     `json:"method,omitempty"` // rpc name, e.g. FindSuccessor
+	Peer      string     `json:"peer,omitempty"`   // ip:port or ip of the remote node
+	Direction Direction  `json:"direction,omitempty"`
+	Action    Action     `json:"action"`
+	Delay     Duration   `json:"delay,omitempty"`
+	Code      codes.Code `json:"code,omitempty"` // status code of error action, default unavailable
+	// Probability of injecting the fault, 0 means always
+	Probability float64 `json:"probability,omitempty"`
+}
+
+// Validate checks if the rule is well formed
+func (r Rule) Validate() error {
+	switch r.Action {
+	case ActionDelay:
+		if r.Delay <= 0 {
+			return fmt.Errorf("fault: delay rule requires positive delay")
+		}
+	case ActionDrop, ActionDuplicate, ActionError:
+	default:
+		return fmt.Errorf("fault: unknown action %q", r.Action)
+	}
+	switch r.Direction {
+	case DirectionAny, DirectionOutgoing, DirectionIncoming:
+	default:
+		return fmt.Errorf("fault: unknown direction %q", r.Direction)
+	}
+	if r.Probability < 0 || r.Probability > 1 {
+		return fmt.Errorf("fault: probability %v is out of [0, 1]", r.Probability)
+	}
+	return nil
+}
+
+// matches check if the rule applies to the rpc
+// peer of incoming rpcs has the caller's ephemeral port, so rules can match the ip only
+func (r Rule) matches(direction Direction, method string, peer string) bool {
+	if r.Direction != DirectionAny && r.Direction != direction {
+		return false
+	}
+	if r.Method != "" && r.Method != method {
+		return false
+	}
+	if r.Peer == "" || r.Peer == peer {
+		return true
+	}
+	host, _, err := net.SplitHostPort(peer)
+	return err == nil && r.Peer == host
+}
+
+// ParseRules decodes json list of rules and validates them
+func ParseRules(content []byte) ([]Rule, error) {
+	rules := []Rule{}
+	if err := json.Unmarshal(content, &rules); err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// LoadRules loads rules from json file
+// [{"method": "Notify", "peer": "10.0.0.2:10001", "action": "drop", "probability": 0.5}]
+func LoadRules(file string) ([]Rule, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(content)
+}
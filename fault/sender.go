@@ -0,0 +1,219 @@
+package fault
+
+import (
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/helpers"
+)
+
+// Sender injects faults into rpcs of the wrapped sender
+// rpcs are matched by the name of the grpc method and the address of the remote node
+type Sender struct {
+	next     chord.RemoteNodeSenderInterface
+	injector *Injector
+}
+
+// NewSender wraps the sender to inject faults of the injector
+func NewSender(next chord.RemoteNodeSenderInterface, injector *Injector) *Sender {
+	return &Sender{
+		next:     next,
+		injector: injector,
+	}
+}
+
+// FindSuccessor find closest node to the given key in remote node
+// ref D
+func (s *Sender) FindSuccessor(remote *chord.RemoteNode, identifier [helpers.HashSize]byte) (*chord.Node, error) {
+	duplicate, err := s.inject("FindSuccessor", remote)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		s.next.FindSuccessor(remote, identifier)
+	}
+	return s.next.FindSuccessor(remote, identifier)
+}
+
+// GetStablizerData successor's (successor list and predecessor)
+// ref E.3
+func (s *Sender) GetStablizerData(remote *chord.RemoteNode, local *chord.Node) (*chord.Node, *chord.SuccessorList, error) {
+	duplicate, err := s.inject("GetStablizerData", remote)
+	if err != nil {
+		return nil, nil, err
+	}
+	if duplicate {
+		s.next.GetStablizerData(remote, local)
+	}
+	predecessor, successorList, err := s.next.GetStablizerData(remote, local)
+	return predecessor, s.bindSuccessorList(successorList), err
+}
+
+// GetSuccessor remote node's successor
+func (s *Sender) GetSuccessor(remote *chord.RemoteNode) (*chord.Node, error) {
+	duplicate, err := s.inject("GetSuccessor", remote)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		s.next.GetSuccessor(remote)
+	}
+	return s.next.GetSuccessor(remote)
+}
+
+// GetPredecessor remote node's predecessor
+func (s *Sender) GetPredecessor(remote *chord.RemoteNode) (*chord.Node, error) {
+	duplicate, err := s.inject("GetPredecessor", remote)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		s.next.GetPredecessor(remote)
+	}
+	return s.next.GetPredecessor(remote)
+}
+
+// GetSuccessorList remote node's successor list
+func (s *Sender) GetSuccessorList(remote *chord.RemoteNode) (*chord.SuccessorList, error) {
+	duplicate, err := s.inject("GetSuccessorList", remote)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		s.next.GetSuccessorList(remote)
+	}
+	successorList, err := s.next.GetSuccessorList(remote)
+	return s.bindSuccessorList(successorList), err
+}
+
+// Notify update predecessor
+// ref E.1
+func (s *Sender) Notify(remote *chord.RemoteNode, local *chord.Node) error {
+	duplicate, err := s.inject("Notify", remote)
+	if err != nil {
+		return err
+	}
+	if duplicate {
+		s.next.Notify(remote, local)
+	}
+	return s.next.Notify(remote, local)
+}
+
+// Ping check if remote node is reachable and ready, failed pings return false
+// ref E.1
+func (s *Sender) Ping(remote *chord.RemoteNode) bool {
+	duplicate, err := s.inject("Ping", remote)
+	if err != nil {
+		return false
+	}
+	if duplicate {
+		s.next.Ping(remote)
+	}
+	return s.next.Ping(remote)
+}
+
+func (s *Sender) GlobalMaintenance(remote *chord.RemoteNode, data []byte) ([]byte, error) {
+	duplicate, err := s.inject("GlobalMaintenance", remote)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		s.next.GlobalMaintenance(remote, data)
+	}
+	return s.next.GlobalMaintenance(remote, data)
+}
+
+// Merge asks remote node to place candidate in its ring
+func (s *Sender) Merge(remote *chord.RemoteNode, local *chord.Node, candidate *chord.Node, hops int) error {
+	duplicate, err := s.inject("Merge", remote)
+	if err != nil {
+		return err
+	}
+	if duplicate {
+		s.next.Merge(remote, local, candidate, hops)
+	}
+	return s.next.Merge(remote, local, candidate, hops)
+}
+
+// Store store data in remote node
+func (s *Sender) Store(remote *chord.RemoteNode, data []byte) (bool, error) {
+	duplicate, err := s.inject("Store", remote)
+	if err != nil {
+		return false, err
+	}
+	if duplicate {
+		s.next.Store(remote, data)
+	}
+	return s.next.Store(remote, data)
+}
+
+// StoreReplica push record to remote node as a replica
+func (s *Sender) StoreReplica(remote *chord.RemoteNode, data []byte) (bool, error) {
+	duplicate, err := s.inject("StoreReplica", remote)
+	if err != nil {
+		return false, err
+	}
+	if duplicate {
+		s.next.StoreReplica(remote, data)
+	}
+	return s.next.StoreReplica(remote, data)
+}
+
+// Fetch retreive data from remote node
+func (s *Sender) Fetch(remote *chord.RemoteNode, namespace string, key [helpers.HashSize]byte) ([]byte, error) {
+	duplicate, err := s.inject("Fetch", remote)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		s.next.Fetch(remote, namespace, key)
+	}
+	return s.next.Fetch(remote, namespace, key)
+}
+
+// GetPredecessorList predecessor's (predecessor list)
+func (s *Sender) GetPredecessorList(remote *chord.RemoteNode, local *chord.Node) (*chord.PredecessorList, error) {
+	duplicate, err := s.inject("GetPredecessorList", remote)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		s.next.GetPredecessorList(remote, local)
+	}
+	predecessorList, err := s.next.GetPredecessorList(remote, local)
+	if predecessorList == nil {
+		return nil, err
+	}
+	nodes := chord.NewPredecessorList()
+	for i, node := range predecessorList.GetNodes() {
+		nodes.Nodes[i] = chord.NewRemoteNode(node.Node, s)
+	}
+	return nodes, err
+}
+
+// TransferRange receives records ∈ (from, to] of remote node in batches
+// duplicate delivers all batches twice
+func (s *Sender) TransferRange(remote *chord.RemoteNode, local *chord.Node, from [helpers.HashSize]byte, to [helpers.HashSize]byte, receive func(records []*chord.Record) error) error {
+	duplicate, err := s.inject("TransferRange", remote)
+	if err != nil {
+		return err
+	}
+	if duplicate {
+		s.next.TransferRange(remote, local, from, to, receive)
+	}
+	return s.next.TransferRange(remote, local, from, to, receive)
+}
+
+func (s *Sender) inject(method string, remote *chord.RemoteNode) (bool, error) {
+	return s.injector.inject(DirectionOutgoing, method, remote.GetFullAddress())
+}
+
+// bindSuccessorList binds returned nodes to this sender, so their rpcs are injected too
+func (s *Sender) bindSuccessorList(successorList *chord.SuccessorList) *chord.SuccessorList {
+	if successorList == nil {
+		return nil
+	}
+	nodes := chord.NewSuccessorList()
+	for i, node := range successorList.GetNodes() {
+		nodes.Nodes[i] = chord.NewRemoteNode(node.Node, s)
+	}
+	return nodes
+}
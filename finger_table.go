@@ -2,6 +2,7 @@ package chord
 
 import (
 	"math/big"
+	"sort"
 	"sync"
 
 	"github.com/mbrostami/chord/helpers"
@@ -12,11 +13,15 @@ import (
 // ref D - Theorem IV.2
 const MSIZE int = helpers.HashSize * 8
 
+// FingerTable keeps only distinct fingers
+// Table[k] is set only if finger[k] is different from finger[k-1]
 type FingerTable struct {
 	mutex      sync.RWMutex
 	Table      map[int]*RemoteNode // ref D
 	TableIndex int                 // to use in fixFinger
 	m          int
+	suspected  map[[helpers.HashSize]byte]bool // fingers failed during lookup
+	last       *RemoteNode                     // last fixed finger in current round
 }
 
 func NewFingerTable() *FingerTable {
@@ -24,36 +29,141 @@ func NewFingerTable() *FingerTable {
 		Table:      make(map[int]*RemoteNode),
 		TableIndex: 0,
 		m:          MSIZE,
+		suspected:  make(map[[helpers.HashSize]byte]bool),
 	}
 }
 
-func (f *FingerTable) ClosestPrecedingNode(identifier [helpers.HashSize]byte, localNode *Node) *RemoteNode {
+// ClosestPrecedingNodes returns distinct fingers ∈ (n, id)
+// ordered from the closest to the farthest preceding node of the identifier
+// suspected fingers are included, the ring tries them after the others
+// ref D
+func (f *FingerTable) ClosestPrecedingNodes(identifier [helpers.HashSize]byte, localNode *Node) []*RemoteNode {
 	f.mutex.RLock()
 	defer f.mutex.RUnlock()
-	for m := len(f.Table); m > 0; m-- {
-		if f.Table[m] != nil {
-			// finger[i] ∈ (n, id)
-			if helpers.Between(f.Table[m].Identifier, localNode.Identifier, identifier) {
-				return f.Table[m]
-			}
+	indexes := make([]int, 0, len(f.Table))
+	for index := range f.Table {
+		indexes = append(indexes, index)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+	seen := make(map[[helpers.HashSize]byte]bool)
+	nodes := []*RemoteNode{}
+	for _, index := range indexes {
+		finger := f.Table[index]
+		if finger == nil || seen[finger.Identifier] {
+			continue
+		}
+		// finger[i] ∈ (n, id)
+		if helpers.Between(finger.Identifier, localNode.Identifier, identifier) {
+			seen[finger.Identifier] = true
+			nodes = append(nodes, finger)
+		}
+	}
+	return nodes
+}
+
+// Suspect marks the given node as failed, so it will be tried last in lookups
+// until it answers or it is set by fixFingers
+func (f *FingerTable) Suspect(remoteNode *RemoteNode) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.suspected[remoteNode.Identifier] = true
+}
+
+// Unsuspect clears the suspicion of the node, e.g. it answered a lookup
+func (f *FingerTable) Unsuspect(remoteNode *RemoteNode) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.suspected, remoteNode.Identifier)
+}
+
+// IsSuspected check if the given node is marked as failed
+func (f *FingerTable) IsSuspected(remoteNode *RemoteNode) bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.suspected[remoteNode.Identifier]
+}
+
+// Fingers returns a copy of distinct fingers by their index
+func (f *FingerTable) Fingers() map[int]*RemoteNode {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	fingers := make(map[int]*RemoteNode, len(f.Table))
+	for index, finger := range f.Table {
+		if finger != nil {
+			fingers[index] = finger
 		}
 	}
-	return nil
+	return fingers
 }
 
 func (f *FingerTable) Set(index int, remoteNode *RemoteNode) {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 	f.Table[index] = remoteNode
+	delete(f.suspected, remoteNode.Identifier)
+}
+
+// Fix sets the finger which is found in current round
+// duplicate of the previous finger will be removed
+func (f *FingerTable) Fix(index int, remoteNode *RemoteNode) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if index > 1 && f.last != nil && f.last.Identifier == remoteNode.Identifier {
+		delete(f.Table, index)
+	} else {
+		f.Table[index] = remoteNode
+	}
+	delete(f.suspected, remoteNode.Identifier)
+	f.last = remoteNode
+}
+
+// Skip keeps the current finger of the index whose lookup failed
+// it's used as the last fixed finger, so the round moves on to the next fingers
+func (f *FingerTable) Skip(index int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if finger := f.Table[index]; finger != nil {
+		f.last = finger
+	}
 }
 
 // CalculateIdentifier calculates next identifier
+// fingers which are covered by the last fixed finger are skipped and removed,
+// since finger[k] = finger[k-1] if finger[k].start ∈ (n, finger[k-1]]
 func (f *FingerTable) CalculateIdentifier(localNode *Node) (int, [helpers.HashSize]byte) {
-	f.TableIndex++
-	if f.TableIndex > f.m {
-		f.TableIndex = 1
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for {
+		f.TableIndex++
+		if f.TableIndex > f.m { // new round
+			f.TableIndex = 1
+			f.last = nil
+		}
+		identifier := fingerStart(localNode, f.TableIndex)
+		if f.TableIndex > 1 && f.last != nil {
+			// if last finger is the node itself, the rest are the same
+			if f.last.Identifier == localNode.Identifier ||
+				helpers.BetweenR(identifier, localNode.Identifier, f.last.Identifier) {
+				delete(f.Table, f.TableIndex)
+				continue
+			}
+		}
+		return f.TableIndex, identifier
 	}
+}
+
+// Interval returns the finger interval [finger[k].start, finger[k+1].start)
+// any node in this interval can be used as finger[k]
+// ref D - Table I
+func (f *FingerTable) Interval(localNode *Node, index int) ([helpers.HashSize]byte, [helpers.HashSize]byte) {
+	if index >= f.m { // last interval ends with the node itself
+		return fingerStart(localNode, index), localNode.Identifier
+	}
+	return fingerStart(localNode, index), fingerStart(localNode, index+1)
+}
 
+// fingerStart calculates (n + 2 ** k-1) Mod M
+func fingerStart(localNode *Node, index int) [helpers.HashSize]byte {
 	meint := new(big.Int)
 	meint.SetBytes(localNode.Identifier[:])
 
@@ -61,7 +171,7 @@ func (f *FingerTable) CalculateIdentifier(localNode *Node) (int, [helpers.HashSi
 	baseint.SetUint64(2)
 
 	powint := new(big.Int)
-	powint.SetInt64(int64(f.TableIndex - 1))
+	powint.SetInt64(int64(index - 1))
 
 	var biggest [helpers.HashSize + 1]byte
 	for i := range biggest {
@@ -95,5 +205,5 @@ func (f *FingerTable) CalculateIdentifier(localNode *Node) (int, [helpers.HashSi
 	}
 	var identifier [helpers.HashSize]byte
 	copy(identifier[:helpers.HashSize], bytes[:helpers.HashSize])
-	return f.TableIndex, identifier
+	return identifier
 }
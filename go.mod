@@ -4,9 +4,10 @@ go 1.14
 
 require (
 	github.com/golang/protobuf v1.3.3
-	github.com/matryer/moq v0.0.0-20200310130814-7721994d1b54 // indirect
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/sirupsen/logrus v1.6.0
 	go.etcd.io/bbolt v1.3.4
+	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55
 	google.golang.org/grpc v1.29.1
+	gopkg.in/yaml.v2 v2.4.0
 )
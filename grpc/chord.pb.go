@@ -26,8 +26,64 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type Hello struct {
+	Version              uint32   `protobuf:"varint,1,opt,name=Version,proto3" json:"Version,omitempty"`
+	MinVersion           uint32   `protobuf:"varint,2,opt,name=MinVersion,proto3" json:"MinVersion,omitempty"`
+	Features             []string `protobuf:"bytes,3,rep,name=Features,proto3" json:"Features,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Hello) Reset()         { *m = Hello{} }
+func (m *Hello) String() string { return proto.CompactTextString(m) }
+func (*Hello) ProtoMessage()    {}
+func (*Hello) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{0}
+}
+
+func (m *Hello) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Hello.Unmarshal(m, b)
+}
+func (m *Hello) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Hello.Marshal(b, m, deterministic)
+}
+func (m *Hello) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Hello.Merge(m, src)
+}
+func (m *Hello) XXX_Size() int {
+	return xxx_messageInfo_Hello.Size(m)
+}
+func (m *Hello) XXX_DiscardUnknown() {
+	xxx_messageInfo_Hello.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Hello proto.InternalMessageInfo
+
+func (m *Hello) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Hello) GetMinVersion() uint32 {
+	if m != nil {
+		return m.MinVersion
+	}
+	return 0
+}
+
+func (m *Hello) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
+}
+
 type Replication struct {
 	Data                 []byte   `protobuf:"bytes,1,opt,name=Data,proto3" json:"Data,omitempty"`
+	Version              uint32   `protobuf:"varint,2,opt,name=Version,proto3" json:"Version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -37,7 +93,7 @@ func (m *Replication) Reset()         { *m = Replication{} }
 func (m *Replication) String() string { return proto.CompactTextString(m) }
 func (*Replication) ProtoMessage()    {}
 func (*Replication) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{0}
+	return fileDescriptor_541dae51990542ec, []int{1}
 }
 
 func (m *Replication) XXX_Unmarshal(b []byte) error {
@@ -65,8 +121,16 @@ func (m *Replication) GetData() []byte {
 	return nil
 }
 
+func (m *Replication) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type Lookup struct {
 	Key                  []byte   `protobuf:"bytes,1,opt,name=Key,proto3" json:"Key,omitempty"`
+	Namespace            string   `protobuf:"bytes,2,opt,name=Namespace,proto3" json:"Namespace,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -76,7 +140,7 @@ func (m *Lookup) Reset()         { *m = Lookup{} }
 func (m *Lookup) String() string { return proto.CompactTextString(m) }
 func (*Lookup) ProtoMessage()    {}
 func (*Lookup) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{1}
+	return fileDescriptor_541dae51990542ec, []int{2}
 }
 
 func (m *Lookup) XXX_Unmarshal(b []byte) error {
@@ -104,6 +168,13 @@ func (m *Lookup) GetKey() []byte {
 	return nil
 }
 
+func (m *Lookup) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
 type MerkleNode struct {
 	Hash                 []byte   `protobuf:"bytes,1,opt,name=Hash,proto3" json:"Hash,omitempty"`
 	Left                 []byte   `protobuf:"bytes,2,opt,name=Left,proto3" json:"Left,omitempty"`
@@ -117,7 +188,7 @@ func (m *MerkleNode) Reset()         { *m = MerkleNode{} }
 func (m *MerkleNode) String() string { return proto.CompactTextString(m) }
 func (*MerkleNode) ProtoMessage()    {}
 func (*MerkleNode) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{2}
+	return fileDescriptor_541dae51990542ec, []int{3}
 }
 
 func (m *MerkleNode) XXX_Unmarshal(b []byte) error {
@@ -171,7 +242,7 @@ func (m *MerkleTree) Reset()         { *m = MerkleTree{} }
 func (m *MerkleTree) String() string { return proto.CompactTextString(m) }
 func (*MerkleTree) ProtoMessage()    {}
 func (*MerkleTree) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{3}
+	return fileDescriptor_541dae51990542ec, []int{4}
 }
 
 func (m *MerkleTree) XXX_Unmarshal(b []byte) error {
@@ -219,7 +290,7 @@ func (m *ForwardSyncData) Reset()         { *m = ForwardSyncData{} }
 func (m *ForwardSyncData) String() string { return proto.CompactTextString(m) }
 func (*ForwardSyncData) ProtoMessage()    {}
 func (*ForwardSyncData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{4}
+	return fileDescriptor_541dae51990542ec, []int{5}
 }
 
 func (m *ForwardSyncData) XXX_Unmarshal(b []byte) error {
@@ -261,8 +332,316 @@ func (m *ForwardSyncData) GetMerkleTree() *MerkleTree {
 	return nil
 }
 
+type RangeRequest struct {
+	Caller               *Node    `protobuf:"bytes,1,opt,name=Caller,proto3" json:"Caller,omitempty"`
+	From                 []byte   `protobuf:"bytes,2,opt,name=From,proto3" json:"From,omitempty"`
+	To                   []byte   `protobuf:"bytes,3,opt,name=To,proto3" json:"To,omitempty"`
+	After                *Cursor  `protobuf:"bytes,4,opt,name=After,proto3" json:"After,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RangeRequest) Reset()         { *m = RangeRequest{} }
+func (m *RangeRequest) String() string { return proto.CompactTextString(m) }
+func (*RangeRequest) ProtoMessage()    {}
+func (*RangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{6}
+}
+
+func (m *RangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RangeRequest.Unmarshal(m, b)
+}
+func (m *RangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RangeRequest.Marshal(b, m, deterministic)
+}
+func (m *RangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RangeRequest.Merge(m, src)
+}
+func (m *RangeRequest) XXX_Size() int {
+	return xxx_messageInfo_RangeRequest.Size(m)
+}
+func (m *RangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RangeRequest proto.InternalMessageInfo
+
+func (m *RangeRequest) GetCaller() *Node {
+	if m != nil {
+		return m.Caller
+	}
+	return nil
+}
+
+func (m *RangeRequest) GetFrom() []byte {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *RangeRequest) GetTo() []byte {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+func (m *RangeRequest) GetAfter() *Cursor {
+	if m != nil {
+		return m.After
+	}
+	return nil
+}
+
+type Cursor struct {
+	Namespace            string   `protobuf:"bytes,1,opt,name=Namespace,proto3" json:"Namespace,omitempty"`
+	Key                  []byte   `protobuf:"bytes,2,opt,name=Key,proto3" json:"Key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Cursor) Reset()         { *m = Cursor{} }
+func (m *Cursor) String() string { return proto.CompactTextString(m) }
+func (*Cursor) ProtoMessage()    {}
+func (*Cursor) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{7}
+}
+
+func (m *Cursor) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Cursor.Unmarshal(m, b)
+}
+func (m *Cursor) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Cursor.Marshal(b, m, deterministic)
+}
+func (m *Cursor) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Cursor.Merge(m, src)
+}
+func (m *Cursor) XXX_Size() int {
+	return xxx_messageInfo_Cursor.Size(m)
+}
+func (m *Cursor) XXX_DiscardUnknown() {
+	xxx_messageInfo_Cursor.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Cursor proto.InternalMessageInfo
+
+func (m *Cursor) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *Cursor) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type RecordBatch struct {
+	Records              [][]byte `protobuf:"bytes,1,rep,name=Records,proto3" json:"Records,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RecordBatch) Reset()         { *m = RecordBatch{} }
+func (m *RecordBatch) String() string { return proto.CompactTextString(m) }
+func (*RecordBatch) ProtoMessage()    {}
+func (*RecordBatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{8}
+}
+
+func (m *RecordBatch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RecordBatch.Unmarshal(m, b)
+}
+func (m *RecordBatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RecordBatch.Marshal(b, m, deterministic)
+}
+func (m *RecordBatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecordBatch.Merge(m, src)
+}
+func (m *RecordBatch) XXX_Size() int {
+	return xxx_messageInfo_RecordBatch.Size(m)
+}
+func (m *RecordBatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecordBatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecordBatch proto.InternalMessageInfo
+
+func (m *RecordBatch) GetRecords() [][]byte {
+	if m != nil {
+		return m.Records
+	}
+	return nil
+}
+
+type ScanRequest struct {
+	Namespace            string   `protobuf:"bytes,1,opt,name=Namespace,proto3" json:"Namespace,omitempty"`
+	From                 []byte   `protobuf:"bytes,2,opt,name=From,proto3" json:"From,omitempty"`
+	To                   []byte   `protobuf:"bytes,3,opt,name=To,proto3" json:"To,omitempty"`
+	Limit                int32    `protobuf:"varint,4,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ScanRequest) Reset()         { *m = ScanRequest{} }
+func (m *ScanRequest) String() string { return proto.CompactTextString(m) }
+func (*ScanRequest) ProtoMessage()    {}
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{9}
+}
+
+func (m *ScanRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanRequest.Unmarshal(m, b)
+}
+func (m *ScanRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanRequest.Marshal(b, m, deterministic)
+}
+func (m *ScanRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanRequest.Merge(m, src)
+}
+func (m *ScanRequest) XXX_Size() int {
+	return xxx_messageInfo_ScanRequest.Size(m)
+}
+func (m *ScanRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanRequest proto.InternalMessageInfo
+
+func (m *ScanRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetFrom() []byte {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *ScanRequest) GetTo() []byte {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+func (m *ScanRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type MergeRequest struct {
+	Caller               *Node    `protobuf:"bytes,1,opt,name=Caller,proto3" json:"Caller,omitempty"`
+	Candidate            *Node    `protobuf:"bytes,2,opt,name=Candidate,proto3" json:"Candidate,omitempty"`
+	Hops                 int32    `protobuf:"varint,3,opt,name=Hops,proto3" json:"Hops,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MergeRequest) Reset()         { *m = MergeRequest{} }
+func (m *MergeRequest) String() string { return proto.CompactTextString(m) }
+func (*MergeRequest) ProtoMessage()    {}
+func (*MergeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{10}
+}
+
+func (m *MergeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MergeRequest.Unmarshal(m, b)
+}
+func (m *MergeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MergeRequest.Marshal(b, m, deterministic)
+}
+func (m *MergeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MergeRequest.Merge(m, src)
+}
+func (m *MergeRequest) XXX_Size() int {
+	return xxx_messageInfo_MergeRequest.Size(m)
+}
+func (m *MergeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MergeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MergeRequest proto.InternalMessageInfo
+
+func (m *MergeRequest) GetCaller() *Node {
+	if m != nil {
+		return m.Caller
+	}
+	return nil
+}
+
+func (m *MergeRequest) GetCandidate() *Node {
+	if m != nil {
+		return m.Candidate
+	}
+	return nil
+}
+
+func (m *MergeRequest) GetHops() int32 {
+	if m != nil {
+		return m.Hops
+	}
+	return 0
+}
+
+type FaultRules struct {
+	Rules                []byte   `protobuf:"bytes,1,opt,name=Rules,proto3" json:"Rules,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FaultRules) Reset()         { *m = FaultRules{} }
+func (m *FaultRules) String() string { return proto.CompactTextString(m) }
+func (*FaultRules) ProtoMessage()    {}
+func (*FaultRules) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{11}
+}
+
+func (m *FaultRules) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FaultRules.Unmarshal(m, b)
+}
+func (m *FaultRules) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FaultRules.Marshal(b, m, deterministic)
+}
+func (m *FaultRules) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FaultRules.Merge(m, src)
+}
+func (m *FaultRules) XXX_Size() int {
+	return xxx_messageInfo_FaultRules.Size(m)
+}
+func (m *FaultRules) XXX_DiscardUnknown() {
+	xxx_messageInfo_FaultRules.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FaultRules proto.InternalMessageInfo
+
+func (m *FaultRules) GetRules() []byte {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
 type Content struct {
 	Data                 []byte   `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Replica              bool     `protobuf:"varint,2,opt,name=replica,proto3" json:"replica,omitempty"`
+	Caller               *Node    `protobuf:"bytes,3,opt,name=caller,proto3" json:"caller,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -272,7 +651,7 @@ func (m *Content) Reset()         { *m = Content{} }
 func (m *Content) String() string { return proto.CompactTextString(m) }
 func (*Content) ProtoMessage()    {}
 func (*Content) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{5}
+	return fileDescriptor_541dae51990542ec, []int{12}
 }
 
 func (m *Content) XXX_Unmarshal(b []byte) error {
@@ -300,9 +679,26 @@ func (m *Content) GetData() []byte {
 	return nil
 }
 
+func (m *Content) GetReplica() bool {
+	if m != nil {
+		return m.Replica
+	}
+	return false
+}
+
+func (m *Content) GetCaller() *Node {
+	if m != nil {
+		return m.Caller
+	}
+	return nil
+}
+
 type Node struct {
 	IP                   string   `protobuf:"bytes,1,opt,name=IP,proto3" json:"IP,omitempty"`
 	Port                 int32    `protobuf:"varint,2,opt,name=Port,proto3" json:"Port,omitempty"`
+	PublicKey            []byte   `protobuf:"bytes,3,opt,name=PublicKey,proto3" json:"PublicKey,omitempty"`
+	Signature            []byte   `protobuf:"bytes,4,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	Timestamp            int64    `protobuf:"varint,5,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -312,7 +708,7 @@ func (m *Node) Reset()         { *m = Node{} }
 func (m *Node) String() string { return proto.CompactTextString(m) }
 func (*Node) ProtoMessage()    {}
 func (*Node) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{6}
+	return fileDescriptor_541dae51990542ec, []int{13}
 }
 
 func (m *Node) XXX_Unmarshal(b []byte) error {
@@ -347,6 +743,27 @@ func (m *Node) GetPort() int32 {
 	return 0
 }
 
+func (m *Node) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+func (m *Node) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *Node) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
 type StablizerData struct {
 	Predecessor          *Node    `protobuf:"bytes,1,opt,name=Predecessor,proto3" json:"Predecessor,omitempty"`
 	SuccessorList        []*Node  `protobuf:"bytes,2,rep,name=SuccessorList,proto3" json:"SuccessorList,omitempty"`
@@ -359,7 +776,7 @@ func (m *StablizerData) Reset()         { *m = StablizerData{} }
 func (m *StablizerData) String() string { return proto.CompactTextString(m) }
 func (*StablizerData) ProtoMessage()    {}
 func (*StablizerData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{7}
+	return fileDescriptor_541dae51990542ec, []int{14}
 }
 
 func (m *StablizerData) XXX_Unmarshal(b []byte) error {
@@ -405,7 +822,7 @@ func (m *Nodes) Reset()         { *m = Nodes{} }
 func (m *Nodes) String() string { return proto.CompactTextString(m) }
 func (*Nodes) ProtoMessage()    {}
 func (*Nodes) Descriptor() ([]byte, []int) {
-	return fileDescriptor_541dae51990542ec, []int{8}
+	return fileDescriptor_541dae51990542ec, []int{15}
 }
 
 func (m *Nodes) XXX_Unmarshal(b []byte) error {
@@ -433,16 +850,151 @@ func (m *Nodes) GetNodes() []*Node {
 	return nil
 }
 
+type Status struct {
+	Node                 *Node     `protobuf:"bytes,1,opt,name=Node,proto3" json:"Node,omitempty"`
+	Successor            *Node     `protobuf:"bytes,2,opt,name=Successor,proto3" json:"Successor,omitempty"`
+	Predecessor          *Node     `protobuf:"bytes,3,opt,name=Predecessor,proto3" json:"Predecessor,omitempty"`
+	SuccessorList        []*Node   `protobuf:"bytes,4,rep,name=SuccessorList,proto3" json:"SuccessorList,omitempty"`
+	PredecessorList      []*Node   `protobuf:"bytes,5,rep,name=PredecessorList,proto3" json:"PredecessorList,omitempty"`
+	Fingers              []*Finger `protobuf:"bytes,6,rep,name=Fingers,proto3" json:"Fingers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Status) Reset()         { *m = Status{} }
+func (m *Status) String() string { return proto.CompactTextString(m) }
+func (*Status) ProtoMessage()    {}
+func (*Status) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{16}
+}
+
+func (m *Status) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Status.Unmarshal(m, b)
+}
+func (m *Status) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Status.Marshal(b, m, deterministic)
+}
+func (m *Status) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Status.Merge(m, src)
+}
+func (m *Status) XXX_Size() int {
+	return xxx_messageInfo_Status.Size(m)
+}
+func (m *Status) XXX_DiscardUnknown() {
+	xxx_messageInfo_Status.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Status proto.InternalMessageInfo
+
+func (m *Status) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func (m *Status) GetSuccessor() *Node {
+	if m != nil {
+		return m.Successor
+	}
+	return nil
+}
+
+func (m *Status) GetPredecessor() *Node {
+	if m != nil {
+		return m.Predecessor
+	}
+	return nil
+}
+
+func (m *Status) GetSuccessorList() []*Node {
+	if m != nil {
+		return m.SuccessorList
+	}
+	return nil
+}
+
+func (m *Status) GetPredecessorList() []*Node {
+	if m != nil {
+		return m.PredecessorList
+	}
+	return nil
+}
+
+func (m *Status) GetFingers() []*Finger {
+	if m != nil {
+		return m.Fingers
+	}
+	return nil
+}
+
+type Finger struct {
+	Index                int32    `protobuf:"varint,1,opt,name=Index,proto3" json:"Index,omitempty"`
+	Node                 *Node    `protobuf:"bytes,2,opt,name=Node,proto3" json:"Node,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Finger) Reset()         { *m = Finger{} }
+func (m *Finger) String() string { return proto.CompactTextString(m) }
+func (*Finger) ProtoMessage()    {}
+func (*Finger) Descriptor() ([]byte, []int) {
+	return fileDescriptor_541dae51990542ec, []int{17}
+}
+
+func (m *Finger) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Finger.Unmarshal(m, b)
+}
+func (m *Finger) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Finger.Marshal(b, m, deterministic)
+}
+func (m *Finger) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Finger.Merge(m, src)
+}
+func (m *Finger) XXX_Size() int {
+	return xxx_messageInfo_Finger.Size(m)
+}
+func (m *Finger) XXX_DiscardUnknown() {
+	xxx_messageInfo_Finger.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Finger proto.InternalMessageInfo
+
+func (m *Finger) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *Finger) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
 func init() {
+	proto.RegisterType((*Hello)(nil), "grpc.Hello")
 	proto.RegisterType((*Replication)(nil), "grpc.Replication")
 	proto.RegisterType((*Lookup)(nil), "grpc.Lookup")
 	proto.RegisterType((*MerkleNode)(nil), "grpc.MerkleNode")
 	proto.RegisterType((*MerkleTree)(nil), "grpc.MerkleTree")
 	proto.RegisterType((*ForwardSyncData)(nil), "grpc.ForwardSyncData")
+	proto.RegisterType((*RangeRequest)(nil), "grpc.RangeRequest")
+	proto.RegisterType((*Cursor)(nil), "grpc.Cursor")
+	proto.RegisterType((*RecordBatch)(nil), "grpc.RecordBatch")
+	proto.RegisterType((*ScanRequest)(nil), "grpc.ScanRequest")
+	proto.RegisterType((*MergeRequest)(nil), "grpc.MergeRequest")
+	proto.RegisterType((*FaultRules)(nil), "grpc.FaultRules")
 	proto.RegisterType((*Content)(nil), "grpc.Content")
 	proto.RegisterType((*Node)(nil), "grpc.Node")
 	proto.RegisterType((*StablizerData)(nil), "grpc.StablizerData")
 	proto.RegisterType((*Nodes)(nil), "grpc.Nodes")
+	proto.RegisterType((*Status)(nil), "grpc.Status")
+	proto.RegisterType((*Finger)(nil), "grpc.Finger")
 }
 
 func init() {
@@ -450,42 +1002,73 @@ func init() {
 }
 
 var fileDescriptor_541dae51990542ec = []byte{
-	// 551 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x53, 0x4f, 0x6f, 0xd3, 0x4e,
-	0x10, 0x4d, 0xd2, 0xb8, 0xbf, 0x1f, 0xe3, 0xa4, 0xa4, 0x5b, 0x84, 0x22, 0x23, 0x50, 0xd8, 0x43,
-	0x55, 0x0a, 0x72, 0xa2, 0x50, 0x0e, 0x88, 0x1b, 0x85, 0x84, 0x42, 0x1a, 0x45, 0x0e, 0xe2, 0xbe,
-	0xb1, 0x37, 0x89, 0x55, 0xd7, 0x63, 0xad, 0x37, 0xaa, 0xc2, 0x27, 0x80, 0x6f, 0x8d, 0x76, 0x37,
-	0x7f, 0xd6, 0x6e, 0x11, 0xb7, 0xf1, 0xbc, 0xf7, 0x66, 0xe6, 0xcd, 0x8e, 0xc1, 0x0d, 0x97, 0x28,
-	0x22, 0x3f, 0x13, 0x28, 0x91, 0xd4, 0x17, 0x22, 0x0b, 0xbd, 0x67, 0x0b, 0xc4, 0x45, 0xc2, 0xbb,
-	0x3a, 0x37, 0x5b, 0xcd, 0xbb, 0xfc, 0x36, 0x93, 0x6b, 0x43, 0xf1, 0x5e, 0x94, 0xc1, 0x3b, 0xc1,
-	0xb2, 0x8c, 0x8b, 0xdc, 0xe0, 0xf4, 0x25, 0xb8, 0x01, 0xcf, 0x92, 0x38, 0x64, 0x32, 0xc6, 0x94,
-	0x10, 0xa8, 0x7f, 0x62, 0x92, 0xb5, 0xab, 0x9d, 0xea, 0x59, 0x23, 0xd0, 0x31, 0xf5, 0xe0, 0x70,
-	0x84, 0x78, 0xb3, 0xca, 0x48, 0x0b, 0x0e, 0xbe, 0xf1, 0xf5, 0x06, 0x54, 0x21, 0xfd, 0x0a, 0x70,
-	0xcd, 0xc5, 0x4d, 0xc2, 0xc7, 0x18, 0x71, 0xa5, 0xfe, 0xc2, 0xf2, 0xe5, 0x56, 0xad, 0x62, 0x95,
-	0x1b, 0xf1, 0xb9, 0x6c, 0xd7, 0x4c, 0x4e, 0xc5, 0xe4, 0x09, 0x38, 0x41, 0xbc, 0x58, 0xca, 0xf6,
-	0x81, 0x4e, 0x9a, 0x0f, 0x3a, 0xd9, 0xd6, 0xfa, 0x2e, 0x38, 0x27, 0xa7, 0xe0, 0xa4, 0x18, 0xf1,
-	0xbc, 0x5d, 0xed, 0x1c, 0x9c, 0xb9, 0xfd, 0x96, 0xaf, 0xbc, 0xfa, 0xfb, 0x66, 0x81, 0x81, 0x89,
-	0x07, 0xff, 0x0b, 0x44, 0xa9, 0xfb, 0x9a, 0x1e, 0xbb, 0x6f, 0xfa, 0xbb, 0x0a, 0x8f, 0x07, 0x28,
-	0xee, 0x98, 0x88, 0xa6, 0xeb, 0x34, 0x54, 0x6e, 0xd4, 0x3c, 0x91, 0xe5, 0x50, 0xc5, 0xa4, 0x07,
-	0x27, 0x99, 0xe0, 0x11, 0x0f, 0x79, 0x9e, 0xa3, 0x18, 0xc5, 0xb9, 0x5d, 0xee, 0x21, 0x88, 0xf4,
-	0x00, 0x6e, 0x77, 0xb3, 0x6a, 0x1b, 0xa5, 0x11, 0x55, 0x3e, 0xb0, 0x38, 0xf4, 0x39, 0xfc, 0x77,
-	0x89, 0xa9, 0xe4, 0xa9, 0x7c, 0x68, 0x04, 0x7a, 0x0e, 0x75, 0xbd, 0xc2, 0x23, 0xa8, 0x5d, 0x4d,
-	0x34, 0xf2, 0x28, 0xa8, 0x5d, 0x4d, 0x14, 0x77, 0x82, 0xc2, 0xac, 0xcf, 0x09, 0x74, 0x4c, 0x11,
-	0x9a, 0x53, 0xc9, 0x66, 0x49, 0xfc, 0x93, 0x0b, 0xed, 0xe9, 0x0d, 0xb8, 0x93, 0xfd, 0x90, 0x5a,
-	0xed, 0xf6, 0xc1, 0x8c, 0xa3, 0x77, 0x65, 0xc3, 0xa4, 0x07, 0xcd, 0xe9, 0x2a, 0xdc, 0x1b, 0x6a,
-	0xd7, 0xf4, 0x86, 0x6d, 0x7e, 0x91, 0x40, 0x5f, 0x81, 0x33, 0xd6, 0xcb, 0xee, 0x6c, 0x82, 0xcd,
-	0xa3, 0xd8, 0x12, 0x03, 0xf4, 0x7f, 0xd5, 0xc1, 0xb9, 0x54, 0x27, 0x4a, 0x2e, 0xa0, 0x31, 0xe4,
-	0x72, 0x57, 0x88, 0x3c, 0xf5, 0xcd, 0x29, 0xfa, 0xdb, 0x53, 0xf4, 0x3f, 0xab, 0x3b, 0xf5, 0xac,
-	0x22, 0xb4, 0x42, 0x5e, 0x43, 0x73, 0x10, 0xa7, 0xd1, 0x5e, 0xd6, 0x30, 0xb0, 0xb9, 0xc0, 0x12,
-	0xf9, 0x1c, 0x8e, 0x86, 0x5c, 0xda, 0xde, 0x2c, 0xbc, 0xc4, 0xed, 0xc3, 0xe1, 0x18, 0x65, 0x3c,
-	0x5f, 0x17, 0x38, 0xde, 0xbd, 0xa1, 0x3e, 0x22, 0x26, 0x3f, 0x58, 0xb2, 0x52, 0x9a, 0xf7, 0xd0,
-	0xb2, 0x2d, 0xa8, 0x5d, 0xfc, 0xd5, 0x86, 0xbb, 0xaf, 0x9a, 0xd3, 0x0a, 0x79, 0x67, 0xa4, 0x85,
-	0x67, 0xb2, 0x1b, 0x9f, 0x98, 0xb8, 0x40, 0xa0, 0x15, 0xd2, 0x05, 0x52, 0x74, 0xa4, 0x7b, 0xda,
-	0xc2, 0x52, 0x9f, 0x0f, 0x70, 0x3c, 0x4c, 0x70, 0xc6, 0x92, 0x6b, 0x16, 0xab, 0xeb, 0x62, 0x69,
-	0xc8, 0xc9, 0xb1, 0xe1, 0x58, 0x3f, 0xb6, 0x77, 0x3f, 0x45, 0x2b, 0xe4, 0x02, 0x9c, 0xa9, 0x44,
-	0xc1, 0x49, 0xd3, 0xa0, 0x9b, 0x03, 0xfd, 0xc7, 0x56, 0x4e, 0xc1, 0x19, 0x70, 0x19, 0x2e, 0x4b,
-	0x4f, 0x53, 0xac, 0x41, 0x2b, 0xb3, 0x43, 0xad, 0x7e, 0xfb, 0x27, 0x00, 0x00, 0xff, 0xff, 0xf5,
-	0x33, 0x79, 0x8e, 0xb3, 0x04, 0x00, 0x00,
+	// 1041 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0x4d, 0x6f, 0xdb, 0x46,
+	0x13, 0x96, 0x44, 0x51, 0x8e, 0x46, 0x52, 0xe2, 0x6c, 0x82, 0x17, 0x84, 0xde, 0xc2, 0x10, 0xf6,
+	0xe0, 0xa8, 0x1f, 0x90, 0x0d, 0x27, 0x01, 0xda, 0x06, 0x28, 0xd0, 0xb8, 0x95, 0xed, 0xd6, 0x36,
+	0x84, 0x95, 0x91, 0x4b, 0xd1, 0xc3, 0x9a, 0x1c, 0xcb, 0x84, 0x29, 0x2e, 0xbb, 0x5c, 0x22, 0x75,
+	0xce, 0xbd, 0xf4, 0xd6, 0x9f, 0xd4, 0x9f, 0x56, 0xec, 0x2e, 0x29, 0x92, 0x92, 0xdc, 0x34, 0xb7,
+	0xf9, 0xe4, 0xcc, 0x3c, 0x33, 0x3b, 0x43, 0xe8, 0xf9, 0xb7, 0x42, 0x06, 0x93, 0x44, 0x0a, 0x25,
+	0x48, 0x7b, 0x21, 0x13, 0x7f, 0xf8, 0xff, 0x85, 0x10, 0x8b, 0x08, 0x0f, 0x8c, 0xec, 0x3a, 0xbb,
+	0x39, 0xc0, 0x65, 0xa2, 0xee, 0xad, 0xc9, 0x70, 0x6f, 0x5d, 0xf9, 0x5e, 0xf2, 0x24, 0x41, 0x99,
+	0x5a, 0x3d, 0xfd, 0x15, 0xdc, 0x53, 0x8c, 0x22, 0x41, 0x3c, 0xd8, 0x79, 0x87, 0x32, 0x0d, 0x45,
+	0xec, 0x35, 0x47, 0xcd, 0xf1, 0x80, 0x15, 0x2c, 0xd9, 0x03, 0xb8, 0x08, 0xe3, 0x42, 0xd9, 0x32,
+	0xca, 0x8a, 0x84, 0x0c, 0xe1, 0xd1, 0x14, 0xb9, 0xca, 0x24, 0xa6, 0x9e, 0x33, 0x72, 0xc6, 0x5d,
+	0xb6, 0xe2, 0xe9, 0x1b, 0xe8, 0x31, 0x4c, 0xa2, 0xd0, 0xe7, 0x4a, 0x9b, 0x12, 0x68, 0xff, 0xc0,
+	0x15, 0x37, 0x11, 0xfa, 0xcc, 0xd0, 0xd5, 0xc0, 0xad, 0x5a, 0x60, 0xfa, 0x35, 0x74, 0xce, 0x85,
+	0xb8, 0xcb, 0x12, 0xb2, 0x0b, 0xce, 0xcf, 0x78, 0x9f, 0xbb, 0x69, 0x92, 0x7c, 0x06, 0xdd, 0x4b,
+	0xbe, 0xc4, 0x34, 0xe1, 0x3e, 0x1a, 0xbf, 0x2e, 0x2b, 0x05, 0xf4, 0x27, 0x80, 0x0b, 0x94, 0x77,
+	0x11, 0x5e, 0x8a, 0x00, 0x75, 0xd4, 0x53, 0x9e, 0xde, 0x16, 0x51, 0x35, 0xad, 0x65, 0xe7, 0x78,
+	0xa3, 0x8c, 0x6b, 0x9f, 0x19, 0x9a, 0x3c, 0x07, 0x97, 0x85, 0x8b, 0x5b, 0xe5, 0x39, 0x46, 0x68,
+	0x19, 0x3a, 0x2b, 0xbe, 0x75, 0x25, 0x11, 0xc9, 0x3e, 0xb8, 0xb1, 0x08, 0x30, 0xf5, 0x9a, 0x23,
+	0x67, 0xdc, 0x3b, 0xda, 0x9d, 0xe8, 0x16, 0x4c, 0xca, 0x60, 0xcc, 0xaa, 0x35, 0x28, 0x52, 0x08,
+	0x65, 0xe2, 0xda, 0x18, 0x2b, 0x9e, 0xfe, 0xd9, 0x84, 0x27, 0x53, 0x21, 0xdf, 0x73, 0x19, 0xcc,
+	0xef, 0x63, 0xdf, 0xa0, 0x40, 0xa0, 0x1d, 0x54, 0x90, 0xd1, 0x34, 0x39, 0x84, 0x67, 0x89, 0xc4,
+	0x00, 0x7d, 0x4c, 0x53, 0x21, 0xcf, 0xc3, 0xb4, 0xfa, 0xb9, 0x6d, 0x2a, 0x72, 0x08, 0xb0, 0x5c,
+	0xe5, 0x6a, 0xca, 0x58, 0x4b, 0x51, 0xcb, 0x59, 0xc5, 0x86, 0x7e, 0x80, 0x3e, 0xe3, 0xf1, 0x02,
+	0x19, 0xfe, 0x96, 0x61, 0xaa, 0x08, 0x85, 0xce, 0x31, 0x8f, 0x22, 0x94, 0x26, 0x93, 0xde, 0x11,
+	0x58, 0x6f, 0x53, 0x5a, 0xae, 0xd1, 0xb9, 0x4e, 0xa5, 0x58, 0x16, 0xd8, 0x69, 0x9a, 0x3c, 0x86,
+	0xd6, 0x95, 0xc8, 0x81, 0x6b, 0x5d, 0x09, 0x42, 0xc1, 0xfd, 0xfe, 0x46, 0xa1, 0xf4, 0xda, 0xe6,
+	0x33, 0x7d, 0xfb, 0x99, 0xe3, 0x4c, 0xa6, 0x42, 0x32, 0xab, 0xd2, 0xfd, 0xb5, 0x82, 0x7a, 0x37,
+	0x9b, 0x6b, 0xdd, 0x2c, 0xba, 0xdf, 0x5a, 0x75, 0x9f, 0xbe, 0xd0, 0x63, 0xe5, 0x0b, 0x19, 0xbc,
+	0xe5, 0xca, 0xbf, 0xd5, 0x23, 0x64, 0x59, 0xdb, 0x96, 0x3e, 0x2b, 0x58, 0x8a, 0xd0, 0x9b, 0xfb,
+	0x3c, 0x2e, 0xaa, 0xfb, 0xf7, 0x38, 0xff, 0xa5, 0xae, 0xe7, 0xe0, 0x9e, 0x87, 0xcb, 0x50, 0x99,
+	0xba, 0x5c, 0x66, 0x19, 0x9a, 0x40, 0xff, 0x02, 0xe5, 0xa7, 0xa1, 0x38, 0x86, 0xee, 0x31, 0x8f,
+	0x83, 0x30, 0xe0, 0xca, 0x4e, 0x70, 0xdd, 0xac, 0x54, 0x9a, 0xf9, 0x15, 0x49, 0x6a, 0xb2, 0x70,
+	0x99, 0xa1, 0x29, 0x05, 0x98, 0xf2, 0x2c, 0x52, 0x2c, 0x8b, 0x30, 0x35, 0x93, 0xab, 0x89, 0x7c,
+	0x7c, 0x2c, 0x43, 0x7f, 0x81, 0x9d, 0x63, 0x11, 0x2b, 0x8c, 0xd5, 0xd6, 0xf1, 0xf2, 0x60, 0x47,
+	0xda, 0xb7, 0x69, 0xc2, 0x3f, 0x62, 0x05, 0xab, 0xd3, 0xf7, 0x6d, 0xfa, 0xce, 0x66, 0xfa, 0x56,
+	0x43, 0xff, 0x68, 0x42, 0x5b, 0x0b, 0x34, 0x42, 0x67, 0xb3, 0x1c, 0xcc, 0xd6, 0xd9, 0x4c, 0x87,
+	0x9a, 0x09, 0x69, 0x5f, 0x96, 0xcb, 0x0c, 0xad, 0x71, 0x9f, 0x65, 0xd7, 0x51, 0xe8, 0xeb, 0x3e,
+	0x5a, 0x30, 0x4b, 0x81, 0xd6, 0xce, 0xc3, 0x45, 0x6c, 0x56, 0x86, 0xc1, 0xb5, 0xcf, 0x4a, 0x81,
+	0xd6, 0x5e, 0x85, 0x4b, 0x4c, 0x15, 0x5f, 0x26, 0x9e, 0x3b, 0x6a, 0x8e, 0x1d, 0x56, 0x0a, 0xa8,
+	0x80, 0xc1, 0x5c, 0xf1, 0xeb, 0x28, 0xfc, 0x80, 0xd2, 0x3c, 0xa4, 0xaf, 0xa0, 0x37, 0x2b, 0x5f,
+	0xc6, 0x16, 0xfc, 0xab, 0x6a, 0x72, 0x08, 0x83, 0x79, 0xe6, 0x97, 0xaf, 0xc8, 0x6b, 0x8d, 0x9c,
+	0x35, 0xfb, 0xba, 0x01, 0xfd, 0x1c, 0xdc, 0x4b, 0xf3, 0xc2, 0x47, 0x39, 0xe1, 0x35, 0x37, 0x5c,
+	0xac, 0x82, 0xfe, 0xd5, 0x82, 0xce, 0x5c, 0x71, 0x95, 0xa5, 0x64, 0xcf, 0x82, 0xb5, 0x25, 0x1d,
+	0x0b, 0xe2, 0x18, 0xba, 0xab, 0x30, 0xdb, 0x86, 0x61, 0xa5, 0x5c, 0xaf, 0xcf, 0xf9, 0xc4, 0xfa,
+	0xda, 0x1f, 0xa9, 0x8f, 0xbc, 0x82, 0x27, 0xb3, 0xfa, 0x66, 0xf1, 0xdc, 0x0d, 0x9f, 0x75, 0x13,
+	0xb2, 0x0f, 0x3b, 0xd3, 0x30, 0x5e, 0xa0, 0x4c, 0xbd, 0xce, 0xc8, 0x29, 0x1f, 0xbc, 0x15, 0xb2,
+	0x42, 0x49, 0xbf, 0x83, 0x8e, 0x25, 0xf5, 0xc8, 0x9e, 0xc5, 0x01, 0xfe, 0x6e, 0x20, 0x71, 0x99,
+	0x65, 0x56, 0x38, 0xb5, 0xb6, 0xe3, 0x74, 0xf4, 0x77, 0x07, 0xdc, 0x63, 0x7d, 0x01, 0xc9, 0x0b,
+	0xe8, 0x9e, 0xf2, 0x38, 0x48, 0x6f, 0xf9, 0x1d, 0x92, 0x9e, 0x35, 0x34, 0x97, 0x6c, 0x58, 0x65,
+	0x68, 0x83, 0xbc, 0x82, 0xfe, 0x09, 0xaa, 0x12, 0xc0, 0xff, 0x4d, 0xec, 0x49, 0x9c, 0x14, 0x27,
+	0x71, 0xf2, 0xa3, 0xbe, 0x97, 0xc3, 0x4a, 0x30, 0xda, 0x20, 0x5f, 0xc2, 0x60, 0x1a, 0xc6, 0x41,
+	0xe9, 0x96, 0x17, 0x64, 0x0f, 0xd2, 0x9a, 0xf1, 0x17, 0xf0, 0xf8, 0x04, 0x55, 0x15, 0xf7, 0x8a,
+	0x7e, 0xcd, 0xf6, 0x08, 0x3a, 0x97, 0x42, 0x85, 0x37, 0xf7, 0x35, 0x9b, 0xe1, 0x46, 0x52, 0x6f,
+	0x85, 0x88, 0xde, 0xf1, 0x28, 0xd3, 0x3e, 0xdf, 0xc0, 0x6e, 0xb5, 0x04, 0x83, 0xf8, 0x43, 0x65,
+	0xf4, 0xca, 0xaf, 0xa6, 0xb4, 0x41, 0x5e, 0x5b, 0xd7, 0xda, 0x13, 0xa9, 0x06, 0x7e, 0x66, 0xe9,
+	0x9a, 0x01, 0x6d, 0x90, 0x03, 0x20, 0xf5, 0x8a, 0x4c, 0xcc, 0xaa, 0xe3, 0x5a, 0x9c, 0x37, 0xf0,
+	0xf4, 0x24, 0x12, 0xd7, 0x3c, 0xba, 0xe0, 0xa1, 0x5e, 0x39, 0x3c, 0xf6, 0x91, 0x3c, 0xb5, 0x36,
+	0x95, 0x3f, 0x80, 0xe1, 0xa6, 0xc8, 0xb4, 0xc8, 0x9d, 0x2b, 0x21, 0x91, 0x0c, 0xf2, 0x33, 0x61,
+	0xb7, 0xd6, 0x47, 0x50, 0xd9, 0x07, 0x77, 0x8a, 0x7a, 0xfd, 0xd7, 0x5b, 0x53, 0xff, 0x06, 0x6d,
+	0x90, 0x6f, 0x61, 0x70, 0x25, 0x79, 0x9c, 0xde, 0xa0, 0x34, 0xa7, 0x8e, 0x90, 0x3c, 0x87, 0xca,
+	0xdd, 0x2b, 0xf3, 0x5a, 0x5d, 0x15, 0xda, 0x38, 0x6c, 0x92, 0xd7, 0xe0, 0x9a, 0xc5, 0x5e, 0xf8,
+	0x54, 0xb7, 0xfc, 0xf0, 0x81, 0x16, 0x18, 0xd4, 0x07, 0x73, 0x54, 0x95, 0x05, 0x9d, 0x1f, 0xe1,
+	0x52, 0x32, 0xdc, 0x90, 0xd0, 0x06, 0x79, 0x09, 0x5d, 0xdb, 0x2c, 0xbd, 0x32, 0x1e, 0x6a, 0x70,
+	0x7f, 0xd5, 0x31, 0x95, 0x69, 0xa7, 0x09, 0xb4, 0xf5, 0x89, 0x2b, 0xc0, 0xae, 0x9c, 0xbb, 0xad,
+	0x45, 0x5d, 0x77, 0xcc, 0xf7, 0x5e, 0xfe, 0x33, 0x00, 0xd8, 0x64, 0x22, 0x55, 0x4a, 0x0a, 0x00,
+	0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -500,6 +1083,7 @@ const _ = grpc.SupportPackageIsVersion6
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type ChordClient interface {
+	Handshake(ctx context.Context, in *Hello, opts ...grpc.CallOption) (*Hello, error)
 	GetSuccessor(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Node, error)
 	FindSuccessor(ctx context.Context, in *Lookup, opts ...grpc.CallOption) (*Node, error)
 	GetPredecessor(ctx context.Context, in *Node, opts ...grpc.CallOption) (*Node, error)
@@ -510,6 +1094,11 @@ type ChordClient interface {
 	GlobalMaintenance(ctx context.Context, in *Replication, opts ...grpc.CallOption) (*Replication, error)
 	Store(ctx context.Context, in *Content, opts ...grpc.CallOption) (*wrappers.BoolValue, error)
 	Fetch(ctx context.Context, in *Lookup, opts ...grpc.CallOption) (*Content, error)
+	TransferRange(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (Chord_TransferRangeClient, error)
+	Merge(ctx context.Context, in *MergeRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	SetFaultRules(ctx context.Context, in *FaultRules, opts ...grpc.CallOption) (*FaultRules, error)
+	GetStatus(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Status, error)
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*RecordBatch, error)
 }
 
 type chordClient struct {
@@ -520,6 +1109,15 @@ func NewChordClient(cc grpc.ClientConnInterface) ChordClient {
 	return &chordClient{cc}
 }
 
+func (c *chordClient) Handshake(ctx context.Context, in *Hello, opts ...grpc.CallOption) (*Hello, error) {
+	out := new(Hello)
+	err := c.cc.Invoke(ctx, "/grpc.Chord/Handshake", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *chordClient) GetSuccessor(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Node, error) {
 	out := new(Node)
 	err := c.cc.Invoke(ctx, "/grpc.Chord/GetSuccessor", in, out, opts...)
@@ -610,8 +1208,77 @@ func (c *chordClient) Fetch(ctx context.Context, in *Lookup, opts ...grpc.CallOp
 	return out, nil
 }
 
+func (c *chordClient) TransferRange(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (Chord_TransferRangeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Chord_serviceDesc.Streams[0], "/grpc.Chord/TransferRange", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chordTransferRangeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Chord_TransferRangeClient interface {
+	Recv() (*RecordBatch, error)
+	grpc.ClientStream
+}
+
+type chordTransferRangeClient struct {
+	grpc.ClientStream
+}
+
+func (x *chordTransferRangeClient) Recv() (*RecordBatch, error) {
+	m := new(RecordBatch)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chordClient) Merge(ctx context.Context, in *MergeRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/grpc.Chord/Merge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chordClient) SetFaultRules(ctx context.Context, in *FaultRules, opts ...grpc.CallOption) (*FaultRules, error) {
+	out := new(FaultRules)
+	err := c.cc.Invoke(ctx, "/grpc.Chord/SetFaultRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chordClient) GetStatus(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/grpc.Chord/GetStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chordClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*RecordBatch, error) {
+	out := new(RecordBatch)
+	err := c.cc.Invoke(ctx, "/grpc.Chord/Scan", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChordServer is the server API for Chord service.
 type ChordServer interface {
+	Handshake(context.Context, *Hello) (*Hello, error)
 	GetSuccessor(context.Context, *empty.Empty) (*Node, error)
 	FindSuccessor(context.Context, *Lookup) (*Node, error)
 	GetPredecessor(context.Context, *Node) (*Node, error)
@@ -622,12 +1289,20 @@ type ChordServer interface {
 	GlobalMaintenance(context.Context, *Replication) (*Replication, error)
 	Store(context.Context, *Content) (*wrappers.BoolValue, error)
 	Fetch(context.Context, *Lookup) (*Content, error)
+	TransferRange(*RangeRequest, Chord_TransferRangeServer) error
+	Merge(context.Context, *MergeRequest) (*empty.Empty, error)
+	SetFaultRules(context.Context, *FaultRules) (*FaultRules, error)
+	GetStatus(context.Context, *empty.Empty) (*Status, error)
+	Scan(context.Context, *ScanRequest) (*RecordBatch, error)
 }
 
 // UnimplementedChordServer can be embedded to have forward compatible implementations.
 type UnimplementedChordServer struct {
 }
 
+func (*UnimplementedChordServer) Handshake(ctx context.Context, req *Hello) (*Hello, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
+}
 func (*UnimplementedChordServer) GetSuccessor(ctx context.Context, req *empty.Empty) (*Node, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetSuccessor not implemented")
 }
@@ -658,11 +1333,44 @@ func (*UnimplementedChordServer) Store(ctx context.Context, req *Content) (*wrap
 func (*UnimplementedChordServer) Fetch(ctx context.Context, req *Lookup) (*Content, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Fetch not implemented")
 }
+func (*UnimplementedChordServer) TransferRange(req *RangeRequest, srv Chord_TransferRangeServer) error {
+	return status.Errorf(codes.Unimplemented, "method TransferRange not implemented")
+}
+func (*UnimplementedChordServer) Merge(ctx context.Context, req *MergeRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Merge not implemented")
+}
+func (*UnimplementedChordServer) SetFaultRules(ctx context.Context, req *FaultRules) (*FaultRules, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFaultRules not implemented")
+}
+func (*UnimplementedChordServer) GetStatus(ctx context.Context, req *empty.Empty) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (*UnimplementedChordServer) Scan(ctx context.Context, req *ScanRequest) (*RecordBatch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
 
 func RegisterChordServer(s *grpc.Server, srv ChordServer) {
 	s.RegisterService(&_Chord_serviceDesc, srv)
 }
 
+func _Chord_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Hello)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChordServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.Chord/Handshake",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChordServer).Handshake(ctx, req.(*Hello))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Chord_GetSuccessor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(empty.Empty)
 	if err := dec(in); err != nil {
@@ -843,10 +1551,107 @@ func _Chord_Fetch_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Chord_TransferRange_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RangeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChordServer).TransferRange(m, &chordTransferRangeServer{stream})
+}
+
+type Chord_TransferRangeServer interface {
+	Send(*RecordBatch) error
+	grpc.ServerStream
+}
+
+type chordTransferRangeServer struct {
+	grpc.ServerStream
+}
+
+func (x *chordTransferRangeServer) Send(m *RecordBatch) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Chord_Merge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChordServer).Merge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.Chord/Merge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChordServer).Merge(ctx, req.(*MergeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chord_SetFaultRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FaultRules)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChordServer).SetFaultRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.Chord/SetFaultRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChordServer).SetFaultRules(ctx, req.(*FaultRules))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chord_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChordServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.Chord/GetStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChordServer).GetStatus(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chord_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChordServer).Scan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.Chord/Scan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChordServer).Scan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Chord_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "grpc.Chord",
 	HandlerType: (*ChordServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Handshake",
+			Handler:    _Chord_Handshake_Handler,
+		},
 		{
 			MethodName: "GetSuccessor",
 			Handler:    _Chord_GetSuccessor_Handler,
@@ -887,7 +1692,29 @@ var _Chord_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Fetch",
 			Handler:    _Chord_Fetch_Handler,
 		},
+		{
+			MethodName: "Merge",
+			Handler:    _Chord_Merge_Handler,
+		},
+		{
+			MethodName: "SetFaultRules",
+			Handler:    _Chord_SetFaultRules_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _Chord_GetStatus_Handler,
+		},
+		{
+			MethodName: "Scan",
+			Handler:    _Chord_Scan_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "TransferRange",
+			Handler:       _Chord_TransferRange_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "chord.proto",
 }
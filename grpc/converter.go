@@ -5,14 +5,25 @@ import "github.com/mbrostami/chord"
 // ConvertToGrpcNode convert chord node to grpc node
 func ConvertToGrpcNode(node *chord.Node) *Node {
 	grpcNode := &Node{
-		IP:   node.IP,
-		Port: int32(node.Port),
+		IP:        node.IP,
+		Port:      int32(node.Port),
+		PublicKey: node.PublicKey,
+		Signature: node.Signature,
+		Timestamp: node.Timestamp,
 	}
 	return grpcNode
 }
 
 // ConvertToChordNode change grpc node to chord local node
+// identifier is derived from the public key only if the node claim is signed by the key
+// otherwise it's derived from the address, so nodes can't pick their position by claiming a key
 func ConvertToChordNode(node *Node) *chord.Node {
+	if len(node.PublicKey) > 0 && VerifyNodeClaim(node) == nil {
+		chordNode := chord.NewNodeWithPublicKey(node.IP, uint(node.Port), node.PublicKey)
+		chordNode.Signature = node.Signature
+		chordNode.Timestamp = node.Timestamp
+		return chordNode
+	}
 	return chord.NewNode(node.IP, uint(node.Port))
 }
 
@@ -31,7 +42,7 @@ func ConvertToGrpcSuccessorList(slist *chord.SuccessorList) []*Node {
 func ConvertToChordSuccessorList(nlist []*Node, remoteSender chord.RemoteNodeSenderInterface) *chord.SuccessorList {
 	nodes := chord.NewSuccessorList()
 	for i := 0; i < len(nlist); i++ { // keep sorted
-		nodes.Nodes[i] = chord.NewRemoteNode(chord.NewNode(nlist[i].IP, uint(nlist[i].Port)), remoteSender)
+		nodes.Nodes[i] = chord.NewRemoteNode(ConvertToChordNode(nlist[i]), remoteSender)
 	}
 	return nodes
 }
@@ -51,7 +62,50 @@ func ConvertToGrpcPredecessorList(slist *chord.PredecessorList) []*Node {
 func ConvertToChordPredecessorList(nlist []*Node, remoteSender chord.RemoteNodeSenderInterface) *chord.PredecessorList {
 	nodes := chord.NewPredecessorList()
 	for i := 0; i < len(nlist); i++ { // keep sorted
-		nodes.Nodes[i] = chord.NewRemoteNode(chord.NewNode(nlist[i].IP, uint(nlist[i].Port)), remoteSender)
+		nodes.Nodes[i] = chord.NewRemoteNode(ConvertToChordNode(nlist[i]), remoteSender)
 	}
 	return nodes
 }
+
+// ConvertToGrpcStatus change chord ring status to grpc status
+func ConvertToGrpcStatus(status *chord.RingStatus) *Status {
+	grpcStatus := &Status{
+		Node:      ConvertToGrpcNode(status.Node),
+		Successor: ConvertToGrpcNode(status.Successor),
+	}
+	if status.Predecessor != nil {
+		grpcStatus.Predecessor = ConvertToGrpcNode(status.Predecessor)
+	}
+	for _, node := range status.SuccessorList {
+		grpcStatus.SuccessorList = append(grpcStatus.SuccessorList, ConvertToGrpcNode(node))
+	}
+	for _, node := range status.PredecessorList {
+		grpcStatus.PredecessorList = append(grpcStatus.PredecessorList, ConvertToGrpcNode(node))
+	}
+	for index, node := range status.Fingers {
+		grpcStatus.Fingers = append(grpcStatus.Fingers, &Finger{Index: int32(index), Node: ConvertToGrpcNode(node)})
+	}
+	return grpcStatus
+}
+
+// ConvertToChordStatus change grpc status to chord ring status
+func ConvertToChordStatus(status *Status) *chord.RingStatus {
+	chordStatus := &chord.RingStatus{
+		Node:      ConvertToChordNode(status.Node),
+		Successor: ConvertToChordNode(status.Successor),
+		Fingers:   make(map[int]*chord.Node),
+	}
+	if status.Predecessor != nil {
+		chordStatus.Predecessor = ConvertToChordNode(status.Predecessor)
+	}
+	for _, node := range status.SuccessorList {
+		chordStatus.SuccessorList = append(chordStatus.SuccessorList, ConvertToChordNode(node))
+	}
+	for _, node := range status.PredecessorList {
+		chordStatus.PredecessorList = append(chordStatus.PredecessorList, ConvertToChordNode(node))
+	}
+	for _, finger := range status.Fingers {
+		chordStatus.Fingers[int(finger.Index)] = ConvertToChordNode(finger.Node)
+	}
+	return chordStatus
+}
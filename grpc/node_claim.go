@@ -0,0 +1,33 @@
+package grpc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+)
+
+// NodeClaim is the signed content of node: IP, Port, PublicKey, Timestamp
+func NodeClaim(node *Node) []byte {
+	var claim bytes.Buffer
+	claim.WriteString(node.IP)
+	binary.Write(&claim, binary.BigEndian, node.Port)
+	claim.Write(node.PublicKey)
+	binary.Write(&claim, binary.BigEndian, node.Timestamp)
+	return claim.Bytes()
+}
+
+// VerifyNodeClaim check the node claim is signed by its public key
+// age is not checked, so claims relayed by other nodes (e.g. in successor lists) stay valid
+func VerifyNodeClaim(node *Node) error {
+	if len(node.Signature) == 0 {
+		return errors.New("node claim is not signed")
+	}
+	if len(node.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+	if !ed25519.Verify(node.PublicKey, NodeClaim(node), node.Signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package chord
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mbrostami/chord/helpers"
+)
+
+// rttWeight is the weight of the newest sample in the moving average
+// same as TCP smoothed round trip time (RFC 6298)
+const rttWeight float64 = 0.125
+
+// LatencyTable keeps smoothed round trip time of remote nodes
+// samples are collected from ping
+type LatencyTable struct {
+	mutex sync.RWMutex
+	rtt   map[[helpers.HashSize]byte]time.Duration
+}
+
+// NewLatencyTable make new latency table
+func NewLatencyTable() *LatencyTable {
+	return &LatencyTable{
+		rtt: make(map[[helpers.HashSize]byte]time.Duration),
+	}
+}
+
+// Observe adds new round trip time sample of the given node
+func (l *LatencyTable) Observe(identifier [helpers.HashSize]byte, rtt time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	srtt, found := l.rtt[identifier]
+	if !found {
+		l.rtt[identifier] = rtt
+		return
+	}
+	l.rtt[identifier] = time.Duration((1-rttWeight)*float64(srtt) + rttWeight*float64(rtt))
+}
+
+// Get returns smoothed round trip time of the given node
+func (l *LatencyTable) Get(identifier [helpers.HashSize]byte) (time.Duration, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	rtt, found := l.rtt[identifier]
+	return rtt, found
+}
+
+// Identifiers returns identifiers of the nodes which have estimates
+func (l *LatencyTable) Identifiers() [][helpers.HashSize]byte {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	identifiers := make([][helpers.HashSize]byte, 0, len(l.rtt))
+	for identifier := range l.rtt {
+		identifiers = append(identifiers, identifier)
+	}
+	return identifiers
+}
+
+// Forget removes the given node estimates
+func (l *LatencyTable) Forget(identifier [helpers.HashSize]byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.rtt, identifier)
+}
@@ -11,6 +11,11 @@ type Node struct {
 	Identifier [helpers.HashSize]byte
 	IP         string
 	Port       uint
+	PublicKey  []byte
+	// Signature of the node over its address, public key and Timestamp
+	// it's relayed with the node, so other nodes can derive the identifier from the public key
+	Signature []byte
+	Timestamp int64
 }
 
 func NewNode(ip string, port uint) *Node {
@@ -22,6 +27,18 @@ func NewNode(ip string, port uint) *Node {
 	return node
 }
 
+// NewNodeWithPublicKey make new node with identifier derived from the public key
+// so node can't choose its position in the ring by claiming another address
+func NewNodeWithPublicKey(ip string, port uint, publicKey []byte) *Node {
+	node := &Node{
+		IP:         ip,
+		Port:       port,
+		PublicKey:  publicKey,
+		Identifier: helpers.Hash(string(publicKey)),
+	}
+	return node
+}
+
 func (n *Node) GetIP() string {
 	return n.IP
 }
@@ -0,0 +1,102 @@
+package chord
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mbrostami/chord/helpers"
+	"github.com/patrickmn/go-cache"
+)
+
+// LocationCache keeps owners of the identifier ranges found by lookups
+// every lookup result for id means id ∈ (predecessor, owner], so the range [id, owner]
+// belongs to the same owner, and will be extended by next lookups of the same owner
+type LocationCache struct {
+	mutex   sync.Mutex
+	entries *cache.Cache
+	ttl     time.Duration
+	clock   Clock
+}
+
+type locationEntry struct {
+	from    [helpers.HashSize]byte // first identifier known to be owned by the owner
+	owner   *RemoteNode
+	expires time.Time
+}
+
+// NewLocationCache make new location cache, entries expire after ttl
+func NewLocationCache(ttl time.Duration) *LocationCache {
+	return &LocationCache{
+		entries: cache.New(cache.NoExpiration, 0), // expired by the clock
+		ttl:     ttl,
+		clock:   systemClock{},
+	}
+}
+
+// SetClock replaces the source of expiration times
+func (l *LocationCache) SetClock(clock Clock) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.clock = clock
+}
+
+// Add keeps the owner of the identifier
+func (l *LocationCache) Add(identifier [helpers.HashSize]byte, owner *RemoteNode) {
+	if owner == nil || owner.Node == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	key := string(owner.Identifier[:])
+	from := identifier
+	now := l.clock.Now()
+	if x, found := l.entries.Get(key); found && now.Before(x.(*locationEntry).expires) {
+		entry := x.(*locationEntry)
+		// extend the range if id ∈ (owner, entry.from), otherwise id is already in range
+		if !helpers.Between(identifier, owner.Identifier, entry.from) {
+			from = entry.from
+		}
+	}
+	l.entries.Set(key, &locationEntry{from: from, owner: owner, expires: now.Add(l.ttl)}, cache.NoExpiration)
+}
+
+// Lookup returns the owner of the identifier if it's in one of the cached ranges
+func (l *LocationCache) Lookup(identifier [helpers.HashSize]byte) *RemoteNode {
+	l.mutex.Lock()
+	now := l.clock.Now()
+	l.mutex.Unlock()
+	for key, item := range l.entries.Items() {
+		entry := item.Object.(*locationEntry)
+		if !now.Before(entry.expires) {
+			l.entries.Delete(key)
+			continue
+		}
+		// id ∈ [from, owner]
+		if identifier == entry.from || helpers.BetweenR(identifier, entry.from, entry.owner.Identifier) {
+			return entry.owner
+		}
+	}
+	return nil
+}
+
+// Invalidate removes all ranges containing the identifier
+// should be called when the cached owner responds with ErrNotOwner
+// ranges of different owners may overlap after topology changes, so one stale range can hide another
+func (l *LocationCache) Invalidate(identifier [helpers.HashSize]byte) {
+	for key, item := range l.entries.Items() {
+		entry := item.Object.(*locationEntry)
+		if identifier == entry.from || helpers.BetweenR(identifier, entry.from, entry.owner.Identifier) {
+			l.entries.Delete(key)
+		}
+	}
+}
+
+// InvalidateOwner removes the range owned by the given node
+func (l *LocationCache) InvalidateOwner(identifier [helpers.HashSize]byte) {
+	l.entries.Delete(string(identifier[:]))
+}
+
+// Clear removes all ranges, should be called on topology changes
+func (l *LocationCache) Clear() {
+	l.entries.Flush()
+}
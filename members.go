@@ -0,0 +1,104 @@
+package chord
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mbrostami/chord/helpers"
+)
+
+// Members remembers nodes seen by the local node, even after they left successor list and fingers
+// they are probed periodically, so a partitioned ring can be merged when connectivity returns
+type Members struct {
+	mutex sync.Mutex
+	nodes map[[helpers.HashSize]byte]*member
+	last  [helpers.HashSize]byte // members are probed in ring order, starting after the last probed member
+	ttl   time.Duration
+	clock Clock
+}
+
+type member struct {
+	node     *Node
+	lastSeen time.Time
+}
+
+// NewMembers make new members, members are forgotten if they are not seen for ttl
+func NewMembers(ttl time.Duration) *Members {
+	return &Members{
+		nodes: make(map[[helpers.HashSize]byte]*member),
+		ttl:   ttl,
+		clock: systemClock{},
+	}
+}
+
+// SetClock replaces the source of last seen times
+func (m *Members) SetClock(clock Clock) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.clock = clock
+}
+
+// Add remembers the node or refreshes its last seen time
+func (m *Members) Add(node *Node) {
+	if node == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.nodes[node.Identifier] = &member{node: node, lastSeen: m.clock.Now()}
+}
+
+// Remove forgets the node
+func (m *Members) Remove(identifier [helpers.HashSize]byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.nodes, identifier)
+}
+
+// Contains check if the node is remembered
+func (m *Members) Contains(identifier [helpers.HashSize]byte) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	_, found := m.nodes[identifier]
+	return found
+}
+
+// Len returns number of remembered members
+func (m *Members) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.nodes)
+}
+
+// Next returns up to count members to probe, continuing from the last probed member
+// members not seen for ttl are forgotten
+func (m *Members) Next(count int) []*Node {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := m.clock.Now()
+	identifiers := [][helpers.HashSize]byte{}
+	for identifier, member := range m.nodes {
+		if now.Sub(member.lastSeen) > m.ttl {
+			delete(m.nodes, identifier)
+			continue
+		}
+		identifiers = append(identifiers, identifier)
+	}
+	if len(identifiers) == 0 {
+		return nil
+	}
+	// sort by distance from the last probed member, so probing goes around the ring
+	sort.Slice(identifiers, func(i, j int) bool {
+		return identifiers[i] != identifiers[j] && helpers.Between(identifiers[i], m.last, identifiers[j])
+	})
+	if count > len(identifiers) {
+		count = len(identifiers)
+	}
+	nodes := make([]*Node, 0, count)
+	for _, identifier := range identifiers[:count] {
+		nodes = append(nodes, m.nodes[identifier].node)
+	}
+	m.last = identifiers[count-1]
+	return nodes
+}
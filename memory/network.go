@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mbrostami/chord"
+)
+
+// ErrUnreachable is returned when the remote node is not registered,
+// is in another partition or the message is dropped
+var ErrUnreachable = errors.New("remote node is unreachable")
+
+// Network routes calls between rings registered in the same process
+// latency, message drops and partitions can be injected
+type Network struct {
+	mutex      sync.RWMutex
+	rings      map[string]chord.RingInterface // address -> ring
+	partitions map[string]int                 // address -> partition, nodes in different partitions can't talk
+	latency    time.Duration
+	jitter     time.Duration
+	dropRate   float64
+	random     *rand.Rand
+	sleep      func(time.Duration)
+}
+
+// NewNetwork make new in-memory network
+// seed makes drops and jitter reproducible
+func NewNetwork(seed int64) *Network {
+	return &Network{
+		rings:      make(map[string]chord.RingInterface),
+		partitions: make(map[string]int),
+		random:     rand.New(rand.NewSource(seed)),
+		sleep:      time.Sleep,
+	}
+}
+
+// Sender returns the sender of the node with the given address
+// ring of the node should be registered to receive calls
+func (n *Network) Sender(address string) chord.RemoteNodeSenderInterface {
+	return &Sender{
+		network: n,
+		address: address,
+	}
+}
+
+// Register makes the ring reachable by its address
+func (n *Network) Register(ring chord.RingInterface) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.rings[ring.GetLocalNode().GetFullAddress()] = ring
+}
+
+// Unregister makes the node unreachable, like a crashed node
+func (n *Network) Unregister(address string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	delete(n.rings, address)
+	delete(n.partitions, address)
+}
+
+// Rings returns registered rings
+func (n *Network) Rings() []chord.RingInterface {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	rings := make([]chord.RingInterface, 0, len(n.rings))
+	for _, ring := range n.rings {
+		rings = append(rings, ring)
+	}
+	return rings
+}
+
+// SetLatency delays each call by latency ± jitter
+func (n *Network) SetLatency(latency time.Duration, jitter time.Duration) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.latency = latency
+	n.jitter = jitter
+}
+
+// SetDropRate drops the given fraction of calls
+func (n *Network) SetDropRate(rate float64) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.dropRate = rate
+}
+
+// SetSleep replaces the function used to wait for latency, e.g. by a virtual clock
+func (n *Network) SetSleep(sleep func(time.Duration)) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.sleep = sleep
+}
+
+// Partition splits the network, nodes can talk only to the nodes of the same group
+// nodes which are not in any group form another partition
+func (n *Network) Partition(groups ...[]string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.partitions = make(map[string]int)
+	for i, group := range groups {
+		for _, address := range group {
+			n.partitions[address] = i + 1
+		}
+	}
+}
+
+// Heal removes all partitions
+func (n *Network) Heal() {
+	n.Partition()
+}
+
+// route returns the ring of the target address after applying the network faults
+func (n *Network) route(from string, to string) (chord.RingInterface, error) {
+	n.mutex.Lock()
+	ring, found := n.rings[to]
+	partitioned := n.partitions[from] != n.partitions[to]
+	dropped := n.dropRate > 0 && n.random.Float64() < n.dropRate
+	delay := n.latency
+	if n.jitter > 0 {
+		delay += time.Duration(n.random.Int63n(int64(2*n.jitter))) - n.jitter
+	}
+	sleep := n.sleep
+	n.mutex.Unlock()
+	if delay > 0 {
+		sleep(delay)
+	}
+	if !found || partitioned || dropped {
+		return nil, ErrUnreachable
+	}
+	return ring, nil
+}
@@ -0,0 +1,256 @@
+package memory_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/helpers"
+	"github.com/mbrostami/chord/memory"
+)
+
+// maxSteps is the budget of maintenance rounds to converge
+const maxSteps int = 300
+
+// probeEvery is the number of rounds between probes of remembered members
+const probeEvery int = 5
+
+// fingerRounds is the number of rounds to replace fingers which are not reachable anymore
+const fingerRounds int = 10
+
+// clock is a virtual clock, it's advanced by rounds and network latency
+type clock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// testRing is a ring of nodes connected by an in-memory network
+type testRing struct {
+	network *memory.Network
+	clock   *clock
+	rings   []chord.RingInterface
+	step    int
+}
+
+// newTestRing joins size nodes through the first one and runs maintenance until successors are consistent
+func newTestRing(t *testing.T, seed int64, size int) *testRing {
+	dataDir, err := ioutil.TempDir("", "chord-memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dataDir) })
+	c := &clock{now: time.Unix(0, 0)}
+	network := memory.NewNetwork(seed)
+	network.SetSleep(c.Advance)
+	config := chord.DefaultConfig()
+	config.DataDir = dataDir
+	config.Clock = c
+	tr := &testRing{network: network, clock: c}
+	for i := 0; i < size; i++ {
+		node := chord.NewNode(fmt.Sprintf("10.1.%d.%d", i/250, i%250+1), 10001)
+		sender := network.Sender(node.GetFullAddress())
+		ring, err := chord.NewRing(node, sender, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		network.Register(ring)
+		if i == 0 {
+			ring.Create()
+		} else if err := ring.Join(chord.NewRemoteNode(tr.rings[0].GetLocalNode(), sender)); err != nil {
+			t.Fatalf("joining %s failed: %v", node.GetFullAddress(), err)
+		}
+		tr.rings = append(tr.rings, ring)
+	}
+	if steps, ok := tr.runUntilConsistent(tr.rings); !ok {
+		t.Fatalf("ring of %d nodes is not consistent after %d rounds", size, steps)
+	}
+	return tr
+}
+
+// round advances the clock and runs maintenance of all nodes
+func (tr *testRing) round() {
+	tr.step++
+	tr.clock.Advance(time.Second)
+	for _, ring := range tr.rings {
+		ring.Stabilize()
+		ring.CheckPredecessor()
+		ring.FixFingers()
+	}
+	if tr.step%probeEvery == 0 {
+		for _, ring := range tr.rings {
+			ring.ProbeMembers()
+		}
+	}
+}
+
+// runUntilConsistent runs rounds until successors of the rings are the next ones in identifier order
+func (tr *testRing) runUntilConsistent(rings []chord.RingInterface) (int, bool) {
+	for steps := 0; steps < maxSteps; steps++ {
+		if consistent(rings) {
+			return steps, true
+		}
+		tr.round()
+	}
+	return maxSteps, consistent(rings)
+}
+
+// consistent check if successor of each ring is the next node in identifier order
+func consistent(rings []chord.RingInterface) bool {
+	identifiers := sortedIdentifiers(rings)
+	for _, ring := range rings {
+		local := ring.GetLocalNode().Identifier
+		successor := ring.GetSuccessor()
+		if successor == nil || successor.Identifier != expectedSuccessor(identifiers, next(local)) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedIdentifiers(rings []chord.RingInterface) [][helpers.HashSize]byte {
+	identifiers := make([][helpers.HashSize]byte, 0, len(rings))
+	for _, ring := range rings {
+		identifiers = append(identifiers, ring.GetLocalNode().Identifier)
+	}
+	sort.Slice(identifiers, func(i, j int) bool {
+		return bytes.Compare(identifiers[i][:], identifiers[j][:]) < 0
+	})
+	return identifiers
+}
+
+// expectedSuccessor returns the first identifier which is equal to or follows the identifier
+func expectedSuccessor(identifiers [][helpers.HashSize]byte, identifier [helpers.HashSize]byte) [helpers.HashSize]byte {
+	index := sort.Search(len(identifiers), func(i int) bool {
+		return bytes.Compare(identifiers[i][:], identifier[:]) >= 0
+	})
+	if index == len(identifiers) {
+		return identifiers[0]
+	}
+	return identifiers[index]
+}
+
+// next returns identifier + 1, so the node itself is not its own successor
+func next(identifier [helpers.HashSize]byte) [helpers.HashSize]byte {
+	for i := len(identifier) - 1; i >= 0; i-- {
+		identifier[i]++
+		if identifier[i] != 0 {
+			break
+		}
+	}
+	return identifier
+}
+
+func randomIdentifier(random *rand.Rand) [helpers.HashSize]byte {
+	var identifier [helpers.HashSize]byte
+	random.Read(identifier[:])
+	return identifier
+}
+
+// lookup finds the successor of random identifiers from random nodes
+// returns the number of lookups which found nothing, wrong owners fail the test
+func lookup(t *testing.T, random *rand.Rand, rings []chord.RingInterface, lookups int) int {
+	identifiers := sortedIdentifiers(rings)
+	failed := 0
+	for i := 0; i < lookups; i++ {
+		identifier := randomIdentifier(random)
+		ring := rings[random.Intn(len(rings))]
+		successor := ring.FindSuccessorUncached(identifier)
+		if successor == nil {
+			failed++
+			continue
+		}
+		if expected := expectedSuccessor(identifiers, identifier); successor.Identifier != expected {
+			t.Errorf("successor of %x from %s is %x, expected %x", identifier, ring.GetLocalNode().GetFullAddress(), successor.Identifier, expected)
+		}
+	}
+	return failed
+}
+
+func TestFindSuccessor(t *testing.T) {
+	tr := newTestRing(t, 1, 100)
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 5; i++ { // fingers are fixed in more rounds
+		if failed := lookup(t, random, tr.rings, 200); failed > 0 {
+			t.Errorf("%d lookups failed in a stable network", failed)
+		}
+		tr.round()
+	}
+}
+
+func TestFindSuccessorWithDrops(t *testing.T) {
+	tr := newTestRing(t, 2, 100)
+	tr.network.SetDropRate(0.05)
+	random := rand.New(rand.NewSource(2))
+	lookups := 500
+	// lookups fall back to other fingers, so only a few of them fail
+	if failed := lookup(t, random, tr.rings, lookups); failed > lookups/10 {
+		t.Errorf("%d of %d lookups failed", failed, lookups)
+	}
+	tr.network.SetDropRate(0)
+	if steps, ok := tr.runUntilConsistent(tr.rings); !ok {
+		t.Fatalf("ring is not consistent %d rounds after drops stopped", steps)
+	}
+	if failed := lookup(t, random, tr.rings, lookups); failed > 0 {
+		t.Errorf("%d lookups failed after drops stopped", failed)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	tr := newTestRing(t, 3, 100)
+	first := []chord.RingInterface{}
+	second := []chord.RingInterface{}
+	firstAddresses := []string{}
+	secondAddresses := []string{}
+	for i, ring := range tr.rings {
+		if i%2 == 0 {
+			first = append(first, ring)
+			firstAddresses = append(firstAddresses, ring.GetLocalNode().GetFullAddress())
+		} else {
+			second = append(second, ring)
+			secondAddresses = append(secondAddresses, ring.GetLocalNode().GetFullAddress())
+		}
+	}
+	tr.network.Partition(firstAddresses, secondAddresses)
+	random := rand.New(rand.NewSource(3))
+	for _, partition := range [][]chord.RingInterface{first, second} {
+		if steps, ok := tr.runUntilConsistent(partition); !ok {
+			t.Fatalf("partition of %d nodes is not consistent after %d rounds", len(partition), steps)
+		}
+	}
+	// fingers to the other partition are replaced by lookups of FixFingers
+	for i := 0; i < fingerRounds; i++ {
+		tr.round()
+	}
+	for _, partition := range [][]chord.RingInterface{first, second} {
+		if failed := lookup(t, random, partition, 200); failed > 0 {
+			t.Errorf("%d lookups failed in the partition", failed)
+		}
+	}
+
+	tr.network.Heal()
+	if steps, ok := tr.runUntilConsistent(tr.rings); !ok {
+		t.Fatalf("ring is not merged after %d rounds", steps)
+	}
+	if failed := lookup(t, random, tr.rings, 200); failed > 0 {
+		t.Errorf("%d lookups failed after healing", failed)
+	}
+}
@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"errors"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/helpers"
+)
+
+// Sender calls registered rings directly instead of sending rpcs
+// nodes are copied in both directions, like they are serialized over network
+type Sender struct {
+	network *Network
+	address string // address of the local node
+}
+
+// FindSuccessor find closest node to the given key in remote node
+// ref D
+func (s *Sender) FindSuccessor(remoteNode *chord.RemoteNode, identifier [helpers.HashSize]byte) (*chord.Node, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, err
+	}
+	successor := ring.FindSuccessorUncached(identifier)
+	if successor == nil {
+		return nil, chord.ErrLookupFailed
+	}
+	return copyNode(successor.Node), nil
+}
+
+// GetSuccessor remote node's successor
+func (s *Sender) GetSuccessor(remoteNode *chord.RemoteNode) (*chord.Node, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, err
+	}
+	return copyNode(ring.GetSuccessor().Node), nil
+}
+
+// GetPredecessor remote node's predecessor, nil if it's unknown
+func (s *Sender) GetPredecessor(remoteNode *chord.RemoteNode) (*chord.Node, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, err
+	}
+	predecessor := ring.GetCurrentPredecessor()
+	if predecessor == nil {
+		return nil, nil
+	}
+	return copyNode(predecessor.Node), nil
+}
+
+// GetSuccessorList remote node's successor list
+func (s *Sender) GetSuccessorList(remoteNode *chord.RemoteNode) (*chord.SuccessorList, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, err
+	}
+	return s.copySuccessorList(ring.GetSuccessorList()), nil
+}
+
+// GetStablizerData successor's (successor list and predecessor)
+// ref E.3
+func (s *Sender) GetStablizerData(remoteNode *chord.RemoteNode, localNode *chord.Node) (*chord.Node, *chord.SuccessorList, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, nil, err
+	}
+	predecessor, successorList := ring.GetStabilizerData(copyNode(localNode))
+	return copyNode(predecessor.Node), s.copySuccessorList(successorList), nil
+}
+
+// Notify update predecessor
+// ref E.1
+func (s *Sender) Notify(remoteNode *chord.RemoteNode, localNode *chord.Node) error {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return err
+	}
+	if !ring.Notify(copyNode(localNode)) {
+		return errors.New("notify failed")
+	}
+	return nil
+}
+
+// Ping check if remote node is reachable and ready
+// ref E.1
+func (s *Sender) Ping(remoteNode *chord.RemoteNode) bool {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return false
+	}
+	return ring.IsReady()
+}
+
+func (s *Sender) GlobalMaintenance(remoteNode *chord.RemoteNode, data []byte) ([]byte, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, err
+	}
+	return ring.GlobalMaintenance(data)
+}
+
+// Merge asks remote node to place candidate in its ring
+func (s *Sender) Merge(remoteNode *chord.RemoteNode, localNode *chord.Node, candidate *chord.Node, hops int) error {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return err
+	}
+	ring.Merge(copyNode(candidate), hops)
+	return nil
+}
+
+// Store store data in remote node
+func (s *Sender) Store(remoteNode *chord.RemoteNode, data []byte) (bool, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return false, err
+	}
+	return ring.Store(data)
+}
+
+// StoreReplica push record to remote node as a replica
+func (s *Sender) StoreReplica(remoteNode *chord.RemoteNode, data []byte) (bool, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return false, err
+	}
+	return ring.StoreReplica(data)
+}
+
+// Fetch retreive data from remote node
+func (s *Sender) Fetch(remoteNode *chord.RemoteNode, namespace string, key [helpers.HashSize]byte) ([]byte, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, err
+	}
+	return ring.Fetch(namespace, key)
+}
+
+// GetPredecessorList predecessor's (predecessor list)
+func (s *Sender) GetPredecessorList(remoteNode *chord.RemoteNode, localNode *chord.Node) (*chord.PredecessorList, error) {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, err
+	}
+	predecessorList := chord.NewPredecessorList()
+	for i, node := range ring.GetPredecessorList(copyNode(localNode)).GetNodes() {
+		predecessorList.Nodes[i] = chord.NewRemoteNode(copyNode(node.Node), s)
+	}
+	return predecessorList, nil
+}
+
+// TransferRange receives records ∈ (from, to] of remote node in batches
+func (s *Sender) TransferRange(remoteNode *chord.RemoteNode, localNode *chord.Node, from [helpers.HashSize]byte, to [helpers.HashSize]byte, receive func(records []*chord.Record) error) error {
+	ring, err := s.network.route(s.address, remoteNode.GetFullAddress())
+	if err != nil {
+		return err
+	}
+	return ring.TransferRange(from, to, nil, receive)
+}
+
+// copySuccessorList copies successors of the remote ring
+// nodes are bound to this sender to be called from the local node
+func (s *Sender) copySuccessorList(successorList *chord.SuccessorList) *chord.SuccessorList {
+	nodes := chord.NewSuccessorList()
+	for i, node := range successorList.GetNodes() {
+		nodes.Nodes[i] = chord.NewRemoteNode(copyNode(node.Node), s)
+	}
+	return nodes
+}
+
+func copyNode(node *chord.Node) *chord.Node {
+	if node == nil {
+		return nil
+	}
+	nodeCopy := *node
+	return &nodeCopy
+}
@@ -0,0 +1,176 @@
+package net
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mbrostami/chord"
+	"github.com/patrickmn/go-cache"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// aclCacheTTL is the expiration of fetched access control lists
+const aclCacheTTL time.Duration = 10 * time.Second
+
+// anyClient is the identity to give permission to all authenticated clients
+const anyClient string = "*"
+
+// Permission of a client in a namespace, each permission includes the lower ones
+type Permission string
+
+const (
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+	PermissionAdmin Permission = "admin" // can change the access control list of the namespace
+)
+
+var permissionLevels = map[Permission]int{
+	PermissionRead:  1,
+	PermissionWrite: 2,
+	PermissionAdmin: 3,
+}
+
+// Allows check if permission includes the required permission
+func (p Permission) Allows(required Permission) bool {
+	return permissionLevels[p] >= permissionLevels[required]
+}
+
+// NamespaceACL is the access control list of a namespace
+// stored as a record in chord.ACLNamespace, so it's replicated alongside the data
+type NamespaceACL struct {
+	Namespace   string                `json:"namespace"`
+	Permissions map[string]Permission `json:"permissions"` // client identity -> permission
+}
+
+// NewACLRecord makes the record of the access control list to be stored in the ring
+func NewACLRecord(acl *NamespaceACL) *chord.Record {
+	content, _ := json.Marshal(acl)
+	return &chord.Record{
+		CreationTime: time.Now(),
+		Content:      content,
+		Identifier:   chord.RecordIdentifier(chord.ACLNamespace, acl.Namespace),
+		Namespace:    chord.ACLNamespace,
+	}
+}
+
+// AccessControl authorizes authenticated clients per namespace
+// namespaces without access control list are only accessible by admins,
+// except the default namespace which is accessible by all authenticated clients
+type AccessControl struct {
+	ring   chord.RingInterface
+	admins map[string]bool // identities with admin permission in all namespaces, e.g. other nodes
+	cache  *cache.Cache
+}
+
+// NewAccessControl make new access control
+func NewAccessControl(ring chord.RingInterface, admins []string) *AccessControl {
+	accessControl := &AccessControl{
+		ring:   ring,
+		admins: make(map[string]bool),
+		cache:  cache.New(aclCacheTTL, 2*aclCacheTTL),
+	}
+	for _, admin := range admins {
+		accessControl.admins[admin] = true
+	}
+	return accessControl
+}
+
+// Authorize check if client has the required permission in the namespace
+func (a *AccessControl) Authorize(identity string, namespace string, required Permission) error {
+	if a.admins[identity] {
+		return nil
+	}
+	if namespace == chord.ACLNamespace && required == PermissionRead {
+		return nil // access control lists are readable by all authenticated clients
+	}
+	acl, err := a.getACL(namespace)
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if acl == nil {
+		if namespace == "" && required != PermissionAdmin {
+			return nil
+		}
+		return status.Errorf(codes.PermissionDenied, "%s has no %s permission in %q", identity, required, namespace)
+	}
+	permission, found := acl.Permissions[identity]
+	if !found {
+		permission = acl.Permissions[anyClient]
+	}
+	if !permission.Allows(required) {
+		return status.Errorf(codes.PermissionDenied, "%s has no %s permission in %q", identity, required, namespace)
+	}
+	return nil
+}
+
+// IsAdmin check if the identity has admin permission in all namespaces
+func (a *AccessControl) IsAdmin(identity string) bool {
+	return a.admins[identity]
+}
+
+// AuthorizeRecord check if client can store the record
+// storing access control list requires admin permission in its namespace
+// deleting access control list requires admin permission in all namespaces, since tombstone doesn't name the namespace
+func (a *AccessControl) AuthorizeRecord(identity string, record *chord.Record) error {
+	if record.Namespace != chord.ACLNamespace {
+		return a.Authorize(identity, record.Namespace, PermissionWrite)
+	}
+	if record.Deleted {
+		if !a.IsAdmin(identity) {
+			return status.Errorf(codes.PermissionDenied, "%s is not allowed to delete access control lists", identity)
+		}
+		return nil
+	}
+	acl := &NamespaceACL{}
+	if err := json.Unmarshal(record.Content, acl); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid access control list")
+	}
+	if record.Identifier != chord.RecordIdentifier(chord.ACLNamespace, acl.Namespace) {
+		return status.Error(codes.InvalidArgument, "access control list identifier does not match its namespace")
+	}
+	if err := a.Authorize(identity, acl.Namespace, PermissionAdmin); err != nil {
+		return err
+	}
+	a.cache.Delete(acl.Namespace)
+	return nil
+}
+
+// getACL fetches access control list of the namespace from its owner
+// returns nil if namespace has no access control list
+func (a *AccessControl) getACL(namespace string) (*NamespaceACL, error) {
+	if x, found := a.cache.Get(namespace); found {
+		return x.(*NamespaceACL), nil
+	}
+	identifier := chord.RecordIdentifier(chord.ACLNamespace, namespace)
+	owner := a.ring.FindSuccessor(identifier)
+	if owner == nil {
+		return nil, errors.New("owner of access control list is not available")
+	}
+	var data []byte
+	var err error
+	if owner.Identifier == a.ring.GetLocalNode().Identifier {
+		data, err = a.ring.Fetch(chord.ACLNamespace, identifier)
+	} else {
+		data, err = owner.Fetch(chord.ACLNamespace, identifier)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var acl *NamespaceACL
+	if data != nil {
+		record := &chord.Record{}
+		if json.Unmarshal(data, record) != nil {
+			return nil, errors.New("access control list is corrupted")
+		}
+		if !record.Deleted {
+			acl = &NamespaceACL{}
+			if json.Unmarshal(record.Content, acl) != nil {
+				return nil, errors.New("access control list is corrupted")
+			}
+		}
+	}
+	a.cache.Set(namespace, acl, cache.DefaultExpiration)
+	return acl, nil
+}
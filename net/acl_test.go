@@ -0,0 +1,141 @@
+package net
+
+import (
+	"testing"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/helpers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// aclRing owns all keys and serves stored access control lists
+type aclRing struct {
+	chord.RingInterface
+	node    *chord.Node
+	records map[[helpers.HashSize]byte][]byte
+}
+
+func newACLRing(acls ...*NamespaceACL) *aclRing {
+	ring := &aclRing{
+		node:    chord.NewNode("127.0.0.1", 10001),
+		records: make(map[[helpers.HashSize]byte][]byte),
+	}
+	for _, acl := range acls {
+		record := NewACLRecord(acl)
+		ring.records[record.Identifier] = record.GetJson()
+	}
+	return ring
+}
+
+func (r *aclRing) GetLocalNode() *chord.Node {
+	return r.node
+}
+
+func (r *aclRing) FindSuccessor(identifier [helpers.HashSize]byte) *chord.RemoteNode {
+	return chord.NewRemoteNode(r.node, nil)
+}
+
+func (r *aclRing) Fetch(namespace string, key [helpers.HashSize]byte) ([]byte, error) {
+	return r.records[key], nil
+}
+
+func TestAuthorizeNamespacePermissions(t *testing.T) {
+	ring := newACLRing(&NamespaceACL{
+		Namespace: "team",
+		Permissions: map[string]Permission{
+			"alice":   PermissionWrite,
+			"carol":   PermissionAdmin,
+			anyClient: PermissionRead,
+		},
+	})
+	accessControl := NewAccessControl(ring, []string{"node"})
+	allowed := []struct {
+		identity   string
+		permission Permission
+	}{
+		{"alice", PermissionRead},
+		{"alice", PermissionWrite},
+		{"carol", PermissionAdmin},
+		{"bob", PermissionRead},
+		{"node", PermissionAdmin},
+	}
+	for _, c := range allowed {
+		if err := accessControl.Authorize(c.identity, "team", c.permission); err != nil {
+			t.Errorf("%s has no %s permission: %v", c.identity, c.permission, err)
+		}
+	}
+	denied := []struct {
+		identity   string
+		permission Permission
+	}{
+		{"alice", PermissionAdmin},
+		{"bob", PermissionWrite},
+	}
+	for _, c := range denied {
+		if err := accessControl.Authorize(c.identity, "team", c.permission); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s has %s permission, expected PermissionDenied, got %v", c.identity, c.permission, err)
+		}
+	}
+}
+
+func TestAuthorizeNamespaceWithoutACL(t *testing.T) {
+	accessControl := NewAccessControl(newACLRing(), []string{"node"})
+	if err := accessControl.Authorize("bob", "", PermissionWrite); err != nil {
+		t.Errorf("default namespace is not writable: %v", err)
+	}
+	if err := accessControl.Authorize("bob", "", PermissionAdmin); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for admin of default namespace, got %v", err)
+	}
+	if err := accessControl.Authorize("bob", "private", PermissionRead); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for namespace without access control list, got %v", err)
+	}
+	if err := accessControl.Authorize("node", "private", PermissionWrite); err != nil {
+		t.Errorf("admin is denied: %v", err)
+	}
+}
+
+func TestAuthorizeRecordOfACL(t *testing.T) {
+	ring := newACLRing(&NamespaceACL{
+		Namespace:   "team",
+		Permissions: map[string]Permission{"alice": PermissionWrite, "carol": PermissionAdmin},
+	})
+	accessControl := NewAccessControl(ring, []string{"node"})
+	update := NewACLRecord(&NamespaceACL{
+		Namespace:   "team",
+		Permissions: map[string]Permission{"alice": PermissionAdmin},
+	})
+	if err := accessControl.AuthorizeRecord("alice", update); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("writer changed the access control list, got %v", err)
+	}
+	if err := accessControl.AuthorizeRecord("carol", update); err != nil {
+		t.Errorf("namespace admin can't change the access control list: %v", err)
+	}
+	misplaced := NewACLRecord(&NamespaceACL{Namespace: "team"})
+	misplaced.Identifier = chord.RecordIdentifier(chord.ACLNamespace, "other")
+	if err := accessControl.AuthorizeRecord("node", misplaced); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for misplaced access control list, got %v", err)
+	}
+	tombstone := &chord.Record{Namespace: chord.ACLNamespace, Identifier: update.Identifier, Deleted: true}
+	if err := accessControl.AuthorizeRecord("carol", tombstone); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("namespace admin deleted the access control list, got %v", err)
+	}
+	if err := accessControl.AuthorizeRecord("node", tombstone); err != nil {
+		t.Errorf("admin can't delete the access control list: %v", err)
+	}
+}
+
+func TestAuthorizeRecordOfData(t *testing.T) {
+	ring := newACLRing(&NamespaceACL{
+		Namespace:   "team",
+		Permissions: map[string]Permission{"alice": PermissionWrite, "bob": PermissionRead},
+	})
+	accessControl := NewAccessControl(ring, nil)
+	record := &chord.Record{Namespace: "team", Key: "key", Identifier: chord.RecordIdentifier("team", "key")}
+	if err := accessControl.AuthorizeRecord("alice", record); err != nil {
+		t.Errorf("writer can't store the record: %v", err)
+	}
+	if err := accessControl.AuthorizeRecord("bob", record); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("reader stored the record, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package net
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationHeader is the metadata key of the client token
+const authorizationHeader string = "authorization"
+
+// dataMethods are the rpcs which require client authentication
+var dataMethods = map[string]bool{
+	"/grpc.Chord/Store": true,
+	"/grpc.Chord/Fetch": true,
+	"/grpc.Chord/Scan":  true,
+	// range transfer exposes records of all namespaces
+	"/grpc.Chord/TransferRange": true,
+	// admin rpcs
+	"/grpc.Chord/SetFaultRules": true,
+}
+
+type clientIdentityKey struct{}
+
+// Authenticator authenticates clients of data rpcs
+// client identity is taken from the bearer token or mutual tls certificate
+type Authenticator struct {
+	tokens           map[string]string // token -> client identity
+	allowCertificate bool              // use common name of the client certificate as identity
+}
+
+// NewAuthenticator make new authenticator
+func NewAuthenticator(tokens map[string]string, allowCertificate bool) *Authenticator {
+	return &Authenticator{
+		tokens:           tokens,
+		allowCertificate: allowCertificate,
+	}
+}
+
+// LoadTokens loads client tokens from json file {"identity": "token"}
+func LoadTokens(file string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	identities := make(map[string]string)
+	if err := json.Unmarshal(content, &identities); err != nil {
+		return nil, err
+	}
+	tokens := make(map[string]string)
+	for identity, token := range identities {
+		tokens[token] = identity
+	}
+	return tokens, nil
+}
+
+// Authenticate returns the client identity
+func (a *Authenticator) Authenticate(ctx context.Context) (string, error) {
+	var authorization []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		authorization = md.Get(authorizationHeader)
+	}
+	var chains [][]*x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			chains = tlsInfo.State.VerifiedChains
+		}
+	}
+	return a.authenticate(authorization, chains)
+}
+
+// AuthenticateHTTP returns the client identity of the http request
+func (a *Authenticator) AuthenticateHTTP(request *http.Request) (string, error) {
+	var chains [][]*x509.Certificate
+	if request.TLS != nil {
+		chains = request.TLS.VerifiedChains
+	}
+	return a.authenticate(request.Header.Values(authorizationHeader), chains)
+}
+
+// authenticate finds the identity of the bearer token or the verified client certificate
+func (a *Authenticator) authenticate(authorization []string, chains [][]*x509.Certificate) (string, error) {
+	for _, value := range authorization {
+		token := strings.TrimPrefix(value, "Bearer ")
+		if identity, found := a.tokens[token]; found {
+			return identity, nil
+		}
+	}
+	if a.allowCertificate && len(chains) > 0 {
+		return chains[0][0].Subject.CommonName, nil
+	}
+	return "", status.Error(codes.Unauthenticated, "client is not authenticated")
+}
+
+// UnaryInterceptor authenticates data rpcs and keeps client identity in context
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !dataMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		identity, err := a.Authenticate(ctx)
+		if err != nil {
+			log.Warnf("auth: %s rejected: %v", info.FullMethod, err)
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, clientIdentityKey{}, identity), req)
+	}
+}
+
+// StreamInterceptor authenticates streaming data rpcs and keeps client identity in context
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !dataMethods[info.FullMethod] {
+			return handler(srv, stream)
+		}
+		identity, err := a.Authenticate(stream.Context())
+		if err != nil {
+			log.Warnf("auth: %s rejected: %v", info.FullMethod, err)
+			return err
+		}
+		ctx := context.WithValue(stream.Context(), clientIdentityKey{}, identity)
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticatedStream overrides stream context to keep client identity
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// ClientIdentity returns authenticated client identity
+func ClientIdentity(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(string)
+	return identity, ok
+}
+
+// tokenCredentials attaches bearer token to each rpc
+type tokenCredentials struct {
+	token string
+}
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authorizationHeader: "Bearer " + t.token}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
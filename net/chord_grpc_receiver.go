@@ -2,46 +2,210 @@ package net
 
 import (
 	context "context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
+	"sync"
+	"time"
 
+	empty "github.com/golang/protobuf/ptypes/empty"
 	wrappers "github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/fault"
 	chordGrpc "github.com/mbrostami/chord/grpc"
 	"github.com/mbrostami/chord/helpers"
 	log "github.com/sirupsen/logrus"
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
+// chordService is the name of chord service in health checking protocol
+const chordService string = "grpc.Chord"
+
+// healthInterval is the interval of updating health status based on ring readiness
+const healthInterval time.Duration = time.Second
+
+// ReceiverConfig grpc server configuration
+type ReceiverConfig struct {
+	TLS *TLSConfig // nil means insecure
+	// VerifyPeerAddress rejects callers claiming an IP different from the connection's address
+	VerifyPeerAddress bool
+	// RequireSignature rejects callers without signed node claims
+	// node identifier is derived from the signed public key
+	RequireSignature bool
+	// Authenticator authenticates clients of data rpcs, nil means no authentication
+	Authenticator *Authenticator
+	// AccessControl authorizes authenticated clients per namespace, nil means no authorization
+	AccessControl *AccessControl
+	// Faults injects faults into received rpcs, nil means no injection
+	// rules can be replaced by admins through SetFaultRules
+	Faults *fault.Injector
+}
+
 type ChordGrpcReceiver struct {
 	chordGrpc.UnimplementedChordServer
-	ring chord.RingInterface
+	ring   chord.RingInterface
+	config ReceiverConfig
+	server *grpc.Server
+	// certReloader reloads the server certificate, nil means insecure
+	certReloader *certReloader
+	stop         chan struct{} // closed when the server is stopped
+	stopOnce     sync.Once
 }
 
-func NewChordReceiver(ring chord.RingInterface) *ChordGrpcReceiver {
+// NewChordReceiver starts grpc server in background
+// returns when the server is listening, so other nodes can call it right away
+func NewChordReceiver(ring chord.RingInterface, config ReceiverConfig) (*ChordGrpcReceiver, error) {
 	var opts []grpc.ServerOption
+	var certReloader *certReloader
+	if config.TLS != nil {
+		if config.TLS.CertFile == "" {
+			return nil, errors.New("tls: server certificate is required")
+		}
+		reloader, err := newCertReloader(config.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %v", err)
+		}
+		certReloader = reloader
+		opts = append(opts, grpc.Creds(reloader.serverCredentials()))
+	}
+	// accept keepalive pings of idle pooled connections
+	opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             keepaliveMinTime,
+		PermitWithoutStream: true,
+	}))
+	// faults run first, so rejected rpcs are affected like any other message
+	var unaryInterceptors []grpc.UnaryServerInterceptor
+	var streamInterceptors []grpc.StreamServerInterceptor
+	if config.Faults != nil {
+		unaryInterceptors = append(unaryInterceptors, config.Faults.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, config.Faults.StreamServerInterceptor())
+	}
+	if config.Authenticator != nil {
+		unaryInterceptors = append(unaryInterceptors, config.Authenticator.UnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, config.Authenticator.StreamInterceptor())
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(unaryInterceptors...))
+	opts = append(opts, grpc.ChainStreamInterceptor(streamInterceptors...))
 	grpcServer := grpc.NewServer(opts...)
 	chordServer := &ChordGrpcReceiver{
-		ring: ring,
+		ring:         ring,
+		config:       config,
+		server:       grpcServer,
+		certReloader: certReloader,
+		stop:         make(chan struct{}),
 	}
 	chordGrpc.RegisterChordServer(grpcServer, chordServer)
-	listener, _ := net.Listen("tcp", ring.GetLocalNode().GetFullAddress())
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	listener, err := net.Listen("tcp", ring.GetLocalNode().GetFullAddress())
+	if err != nil {
+		if certReloader != nil {
+			certReloader.Close()
+		}
+		return nil, err
+	}
+	go chordServer.updateHealth(healthServer)
 	log.Infof("Start listening on makeNodeServer: %s\n", ring.GetLocalNode().GetFullAddress())
-	grpcServer.Serve(listener)
-	return chordServer
+	go grpcServer.Serve(listener)
+	return chordServer, nil
+}
+
+// Close stops the grpc server, updating its health and reloading its certificate
+func (s *ChordGrpcReceiver) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.server.Stop()
+	if s.certReloader != nil {
+		s.certReloader.Close()
+	}
+}
+
+// updateHealth reflects ring readiness in health status
+// Runs periodically until the receiver is closed
+func (s *ChordGrpcReceiver) updateHealth(healthServer *health.Server) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if s.ring.IsReady() {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+		healthServer.SetServingStatus("", status)
+		healthServer.SetServingStatus(chordService, status)
+		select {
+		case <-s.stop:
+			healthServer.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
 // Notify update predecessor
 // is being called periodically
 func (s *ChordGrpcReceiver) Notify(ctx context.Context, caller *chordGrpc.Node) (*wrappers.BoolValue, error) {
+	if err := s.verifyCaller(ctx, caller); err != nil {
+		return nil, err
+	}
 	result := &wrappers.BoolValue{
 		Value: s.ring.Notify(chordGrpc.ConvertToChordNode(caller)),
 	}
 	return result, nil
 }
 
+// Handshake exchanges protocol version and features
+// compatibility is decided by the caller, skew is only reported here
+func (s *ChordGrpcReceiver) Handshake(ctx context.Context, hello *chordGrpc.Hello) (*chordGrpc.Hello, error) {
+	if hello.Version != ProtocolVersion {
+		addr := "unknown"
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+		log.Warnf("version: skew with caller %s, local version %d, caller version %d", addr, ProtocolVersion, hello.Version)
+	}
+	return localHello(), nil
+}
+
+// GetSuccessor get successor node
+func (s *ChordGrpcReceiver) GetSuccessor(ctx context.Context, request *empty.Empty) (*chordGrpc.Node, error) {
+	return chordGrpc.ConvertToGrpcNode(s.ring.GetSuccessor().Node), nil
+}
+
+// GetPredecessor get predecessor node
+// caller is ignored, unlike GetStablizerData it doesn't replace the predecessor
+func (s *ChordGrpcReceiver) GetPredecessor(ctx context.Context, caller *chordGrpc.Node) (*chordGrpc.Node, error) {
+	predecessor := s.ring.GetCurrentPredecessor()
+	if predecessor == nil {
+		return nil, status.Error(codes.NotFound, "predecessor is unknown")
+	}
+	return chordGrpc.ConvertToGrpcNode(predecessor.Node), nil
+}
+
+// GetSuccessorList get successor list
+func (s *ChordGrpcReceiver) GetSuccessorList(ctx context.Context, request *empty.Empty) (*chordGrpc.Nodes, error) {
+	nodes := &chordGrpc.Nodes{
+		Nodes: chordGrpc.ConvertToGrpcSuccessorList(s.ring.GetSuccessorList()),
+	}
+	return nodes, nil
+}
+
+// GetStatus get the view of the node on the ring, used by clients for introspection
+func (s *ChordGrpcReceiver) GetStatus(ctx context.Context, request *empty.Empty) (*chordGrpc.Status, error) {
+	return chordGrpc.ConvertToGrpcStatus(s.ring.Status()), nil
+}
+
 // GetStablizerData get predecessor node + successor list
 func (s *ChordGrpcReceiver) GetStablizerData(ctx context.Context, caller *chordGrpc.Node) (*chordGrpc.StablizerData, error) {
+	if err := s.verifyCaller(ctx, caller); err != nil {
+		return nil, err
+	}
 	stabilizerData := &chordGrpc.StablizerData{}
 	predecessor, successorList := s.ring.GetStabilizerData(chordGrpc.ConvertToChordNode(caller))
 	stabilizerData.Predecessor = chordGrpc.ConvertToGrpcNode(predecessor.Node)
@@ -51,17 +215,38 @@ func (s *ChordGrpcReceiver) GetStablizerData(ctx context.Context, caller *chordG
 
 // FindSuccessor get closest node to the given key
 func (s *ChordGrpcReceiver) FindSuccessor(ctx context.Context, lookup *chordGrpc.Lookup) (*chordGrpc.Node, error) {
-	successor := s.ring.FindSuccessor(helpers.ConvertToHashSized(lookup.Key))
+	successor := s.ring.FindSuccessorUncached(helpers.ConvertToHashSized(lookup.Key))
 	if successor == nil {
 		log.Error("receiver.FindSuccessor: Successor is null")
-		return nil, errors.New("successor is null")
+		// the node itself is alive, so callers don't fall back to other nodes
+		return nil, status.Error(codes.NotFound, chord.ErrLookupFailed.Error())
 	}
 	return chordGrpc.ConvertToGrpcNode(successor.Node), nil
 }
 
 // Store store data in database
 func (s *ChordGrpcReceiver) Store(ctx context.Context, content *chordGrpc.Content) (*wrappers.BoolValue, error) {
-	stored := s.ring.Store(content.Data)
+	if s.config.AccessControl != nil {
+		record := &chord.Record{}
+		if err := json.Unmarshal(content.Data, record); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid record")
+		}
+		identity, _ := ClientIdentity(ctx)
+		if err := s.config.AccessControl.AuthorizeRecord(identity, record); err != nil {
+			return nil, err
+		}
+	}
+	store := s.ring.Store
+	if content.Replica {
+		if err := s.verifyReplicaCaller(ctx, content.Caller); err != nil {
+			return nil, err
+		}
+		store = s.ring.StoreReplica
+	}
+	stored, err := store(content.Data)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
 	result := &wrappers.BoolValue{
 		Value: stored,
 	}
@@ -70,17 +255,126 @@ func (s *ChordGrpcReceiver) Store(ctx context.Context, content *chordGrpc.Conten
 
 // Fetch get data from database
 func (s *ChordGrpcReceiver) Fetch(ctx context.Context, lookup *chordGrpc.Lookup) (*chordGrpc.Content, error) {
+	if s.config.AccessControl != nil {
+		identity, _ := ClientIdentity(ctx)
+		if err := s.config.AccessControl.Authorize(identity, lookup.Namespace, PermissionRead); err != nil {
+			return nil, err
+		}
+	}
 	var key [helpers.HashSize]byte
 	copy(key[:helpers.HashSize], lookup.Key[:helpers.HashSize])
-	record := s.ring.Fetch(key)
+	record, err := s.ring.Fetch(lookup.Namespace, key)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
 	result := &chordGrpc.Content{
 		Data: record,
 	}
 	return result, nil
 }
 
+// Scan returns a page of records of the namespace
+func (s *ChordGrpcReceiver) Scan(ctx context.Context, request *chordGrpc.ScanRequest) (*chordGrpc.RecordBatch, error) {
+	if s.config.AccessControl != nil {
+		identity, _ := ClientIdentity(ctx)
+		if err := s.config.AccessControl.Authorize(identity, request.Namespace, PermissionRead); err != nil {
+			return nil, err
+		}
+	}
+	from := helpers.ConvertToHashSized(request.From)
+	to := helpers.ConvertToHashSized(request.To)
+	records, err := s.ring.Scan(request.Namespace, from, to, int(request.Limit))
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+	batch := &chordGrpc.RecordBatch{}
+	for _, record := range records {
+		batch.Records = append(batch.Records, record.GetJson())
+	}
+	return batch, nil
+}
+
+// TransferRange streams records of the requested range in batches
+// grpc flow control blocks sending when the receiver is slow
+func (s *ChordGrpcReceiver) TransferRange(request *chordGrpc.RangeRequest, stream chordGrpc.Chord_TransferRangeServer) error {
+	if request.Caller == nil {
+		return status.Error(codes.InvalidArgument, "caller is missing")
+	}
+	if err := s.verifyCaller(stream.Context(), request.Caller); err != nil {
+		return err
+	}
+	if s.config.AccessControl != nil {
+		identity, _ := ClientIdentity(stream.Context())
+		if !s.config.AccessControl.IsAdmin(identity) {
+			return status.Errorf(codes.PermissionDenied, "%s is not allowed to transfer ranges", identity)
+		}
+	}
+	var after *chord.RangeCursor
+	if request.After != nil {
+		after = &chord.RangeCursor{
+			Namespace: request.After.Namespace,
+			Key:       helpers.ConvertToHashSized(request.After.Key),
+		}
+	}
+	from := helpers.ConvertToHashSized(request.From)
+	to := helpers.ConvertToHashSized(request.To)
+	return s.ring.TransferRange(from, to, after, func(records []*chord.Record) error {
+		batch := &chordGrpc.RecordBatch{}
+		for _, record := range records {
+			batch.Records = append(batch.Records, record.GetJson())
+		}
+		return stream.Send(batch)
+	})
+}
+
+// Merge queues candidate to be placed in the ring
+// it's applied by the maintenance, so forwards through many nodes don't hold the caller
+// the candidate's claim is a relayed copy signed at its startup, so only its key is verified, not its age
+// liveness and identifier of the candidate are verified by the ring before it's adopted
+func (s *ChordGrpcReceiver) Merge(ctx context.Context, request *chordGrpc.MergeRequest) (*empty.Empty, error) {
+	if request.Caller == nil || request.Candidate == nil {
+		return nil, status.Error(codes.InvalidArgument, "caller or candidate is missing")
+	}
+	if err := s.verifyCaller(ctx, request.Caller); err != nil {
+		return nil, err
+	}
+	if len(request.Candidate.Signature) > 0 || s.config.RequireSignature {
+		if err := chordGrpc.VerifyNodeClaim(request.Candidate); err != nil {
+			log.Warnf("receiver: merge candidate %s:%d signature is not valid: %v", request.Candidate.IP, request.Candidate.Port, err)
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	s.ring.Merge(chordGrpc.ConvertToChordNode(request.Candidate), int(request.Hops))
+	return &empty.Empty{}, nil
+}
+
+// SetFaultRules replaces fault injection rules of the node and returns the active rules
+// only admins can change the rules, so access control is required
+func (s *ChordGrpcReceiver) SetFaultRules(ctx context.Context, request *chordGrpc.FaultRules) (*chordGrpc.FaultRules, error) {
+	if s.config.Faults == nil {
+		return nil, status.Error(codes.FailedPrecondition, "fault injection is disabled")
+	}
+	identity, _ := ClientIdentity(ctx)
+	if s.config.AccessControl == nil || !s.config.AccessControl.IsAdmin(identity) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not allowed to change fault rules", identity)
+	}
+	rules, err := fault.ParseRules(request.Rules)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.config.Faults.SetRules(rules); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	log.Warnf("receiver.SetFaultRules: %s set %d rules", identity, len(rules))
+	content, _ := json.Marshal(s.config.Faults.Rules())
+	return &chordGrpc.FaultRules{Rules: content}, nil
+}
+
 // GetPredecessorList get predecessor list
 func (s *ChordGrpcReceiver) GetPredecessorList(ctx context.Context, caller *chordGrpc.Node) (*chordGrpc.Nodes, error) {
+	if err := s.verifyCaller(ctx, caller); err != nil {
+		return nil, err
+	}
 	pList := s.ring.GetPredecessorList(chordGrpc.ConvertToChordNode(caller))
 	nodes := &chordGrpc.Nodes{
 		Nodes: chordGrpc.ConvertToGrpcPredecessorList(pList),
@@ -89,7 +383,84 @@ func (s *ChordGrpcReceiver) GetPredecessorList(ctx context.Context, caller *chor
 }
 
 // GlobalMaintenance to sync data from predecessor
+// callers older than syncProtocolVersion are rejected, their digests never match and they drop tombstones
 func (s *ChordGrpcReceiver) GlobalMaintenance(ctx context.Context, replicationRequest *chordGrpc.Replication) (*chordGrpc.Replication, error) {
+	if replicationRequest.Version < syncProtocolVersion {
+		log.Warnf("receiver: rejecting data sync from a peer with version %d", replicationRequest.Version)
+		return nil, status.Errorf(codes.FailedPrecondition, "data sync requires protocol version %d", syncProtocolVersion)
+	}
 	replicationResponse, err := s.ring.GlobalMaintenance(replicationRequest.Data)
 	return &chordGrpc.Replication{Data: replicationResponse}, err
 }
+
+// verifyCaller check if the caller owns the node address it claims
+// using the connection's address, mutual tls certificate and node claim signature
+func (s *ChordGrpcReceiver) verifyCaller(ctx context.Context, caller *chordGrpc.Node) error {
+	if len(caller.Signature) > 0 || s.config.RequireSignature {
+		if err := verifyNodeSignature(caller); err != nil {
+			log.Warnf("receiver: caller %s:%d signature is not valid: %v", caller.IP, caller.Port, err)
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+	if !s.config.VerifyPeerAddress && (s.config.TLS == nil || !s.config.TLS.MutualTLS) {
+		return nil
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "peer is unknown")
+	}
+	if s.config.VerifyPeerAddress && !sameHost(p.Addr, caller.IP) {
+		log.Warnf("receiver: caller %s:%d does not match peer address %s", caller.IP, caller.Port, p.Addr)
+		return status.Error(codes.PermissionDenied, "peer address does not match the node address")
+	}
+	if s.config.TLS == nil || !s.config.TLS.MutualTLS {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return status.Error(codes.Unauthenticated, "peer certificate is missing")
+	}
+	if err := tlsInfo.State.PeerCertificates[0].VerifyHostname(caller.IP); err != nil {
+		log.Warnf("receiver: caller %s:%d does not match peer certificate: %v", caller.IP, caller.Port, err)
+		return status.Error(codes.PermissionDenied, "peer certificate does not match the node address")
+	}
+	return nil
+}
+
+// verifyReplicaCaller check if the replica is pushed by a node
+// replicas skip checks of client records, so clients must not push them
+// caller claim is required, and if access control is enabled the caller must be an admin, like other nodes
+func (s *ChordGrpcReceiver) verifyReplicaCaller(ctx context.Context, caller *chordGrpc.Node) error {
+	if caller == nil {
+		return status.Error(codes.PermissionDenied, "replicas are accepted only from nodes")
+	}
+	if err := s.verifyCaller(ctx, caller); err != nil {
+		return err
+	}
+	if s.config.AccessControl == nil {
+		return nil
+	}
+	identity, _ := ClientIdentity(ctx)
+	if !s.config.AccessControl.IsAdmin(identity) {
+		return status.Errorf(codes.PermissionDenied, "%s is not allowed to store replicas", identity)
+	}
+	return nil
+}
+
+// sameHost check if the connection's address is the same as the claimed ip
+// all loopback addresses are considered the same
+func sameHost(addr net.Addr, ip string) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+	peerIP := net.ParseIP(host)
+	claimedIP := net.ParseIP(ip)
+	if peerIP == nil || claimedIP == nil {
+		return false
+	}
+	if peerIP.IsLoopback() && claimedIP.IsLoopback() {
+		return true
+	}
+	return peerIP.Equal(claimedIP)
+}
@@ -0,0 +1,120 @@
+package net
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/mbrostami/chord"
+	chordGrpc "github.com/mbrostami/chord/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/status"
+)
+
+// testRing records merge requests and stored replicas, other methods of the ring are not used by the tests
+type testRing struct {
+	chord.RingInterface
+	candidates []*chord.Node
+	replicas   [][]byte
+}
+
+func (r *testRing) Merge(candidate *chord.Node, hops int) {
+	r.candidates = append(r.candidates, candidate)
+}
+
+func (r *testRing) IsReady() bool {
+	return true
+}
+
+func (r *testRing) StoreReplica(data []byte) (bool, error) {
+	r.replicas = append(r.replicas, data)
+	return true, nil
+}
+
+// signedClaim returns the claim of a node signed at the given time
+func signedClaim(t *testing.T, ip string, signedAt time.Time) *chordGrpc.Node {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &chordGrpc.Node{IP: ip, Port: 10001, PublicKey: privateKey.Public().(ed25519.PublicKey), Timestamp: signedAt.Unix()}
+	node.Signature = ed25519.Sign(privateKey, chordGrpc.NodeClaim(node))
+	return node
+}
+
+func TestMergeAcceptsCandidateSignedAtStartup(t *testing.T) {
+	ring := &testRing{}
+	receiver := &ChordGrpcReceiver{ring: ring, config: ReceiverConfig{RequireSignature: true}}
+	caller := signedClaim(t, "10.0.0.1", time.Now())
+	// relayed claims are signed once when the node starts
+	candidate := signedClaim(t, "10.0.0.2", time.Now().Add(-2*signatureWindow))
+	if _, err := receiver.Merge(context.Background(), &chordGrpc.MergeRequest{Caller: caller, Candidate: candidate, Hops: 1}); err != nil {
+		t.Fatalf("merge of a candidate signed %v ago failed: %v", 2*signatureWindow, err)
+	}
+	if len(ring.candidates) != 1 {
+		t.Fatalf("candidate is not queued")
+	}
+	if expected := chord.NewNodeWithPublicKey(candidate.IP, uint(candidate.Port), candidate.PublicKey).Identifier; ring.candidates[0].Identifier != expected {
+		t.Errorf("identifier of the candidate is %x, expected %x", ring.candidates[0].Identifier, expected)
+	}
+}
+
+func TestMergeRejectsForgedCandidate(t *testing.T) {
+	ring := &testRing{}
+	receiver := &ChordGrpcReceiver{ring: ring, config: ReceiverConfig{RequireSignature: true}}
+	caller := signedClaim(t, "10.0.0.1", time.Now())
+	candidate := signedClaim(t, "10.0.0.2", time.Now())
+	candidate.IP = "10.0.0.3" // claim of another address
+	_, err := receiver.Merge(context.Background(), &chordGrpc.MergeRequest{Caller: caller, Candidate: candidate, Hops: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+	if len(ring.candidates) != 0 {
+		t.Errorf("forged candidate is queued")
+	}
+}
+
+func TestStoreReplicaFromNode(t *testing.T) {
+	ring := &testRing{}
+	receiver := &ChordGrpcReceiver{ring: ring, config: ReceiverConfig{RequireSignature: true}}
+	content := &chordGrpc.Content{Data: []byte("{}"), Replica: true, Caller: signedClaim(t, "10.0.0.1", time.Now())}
+	if _, err := receiver.Store(context.Background(), content); err != nil {
+		t.Fatalf("replica of a node is rejected: %v", err)
+	}
+	if len(ring.replicas) != 1 {
+		t.Errorf("replica is not stored")
+	}
+}
+
+func TestStoreReplicaRejectsClients(t *testing.T) {
+	ring := &testRing{}
+	receiver := &ChordGrpcReceiver{ring: ring, config: ReceiverConfig{RequireSignature: true}}
+	unsigned := &chordGrpc.Node{IP: "10.0.0.1", Port: 10001}
+	for _, caller := range []*chordGrpc.Node{nil, unsigned} {
+		content := &chordGrpc.Content{Data: []byte("{}"), Replica: true, Caller: caller}
+		if _, err := receiver.Store(context.Background(), content); err == nil {
+			t.Errorf("replica of caller %v is accepted", caller)
+		}
+	}
+	if len(ring.replicas) != 0 {
+		t.Errorf("replicas of clients are stored")
+	}
+}
+
+func TestUpdateHealthStopsOnClose(t *testing.T) {
+	receiver := &ChordGrpcReceiver{ring: &testRing{}, stop: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		receiver.updateHealth(health.NewServer())
+		close(done)
+	}()
+	close(receiver.stop)
+	select {
+	case <-done:
+	case <-time.After(2 * healthInterval):
+		t.Fatal("health is updated after the receiver is closed")
+	}
+}
@@ -2,50 +2,256 @@ package net
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/json"
 	"errors"
-	"net"
+	"io"
+	"sync/atomic"
 	"time"
 
+	empty "github.com/golang/protobuf/ptypes/empty"
+	wrappers "github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/fault"
 	chordGrpc "github.com/mbrostami/chord/grpc"
 	"github.com/mbrostami/chord/helpers"
 	"github.com/patrickmn/go-cache"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
+// pingTimeout is the deadline of health check
+const pingTimeout time.Duration = time.Second
+
+// defaultCallTimeout is the deadline of rpcs if it's not configured
+const defaultCallTimeout time.Duration = 5 * time.Second
+
+// SenderConfig grpc client configuration
+type SenderConfig struct {
+	TLS      *TLSConfig         // nil means insecure
+	Identity ed25519.PrivateKey // signs local node claims if it's set
+	// LocalNode is the caller of rpcs which don't have one in the interface, e.g. successor list of legacy peers
+	// nil for clients, which are not part of the ring
+	LocalNode *chord.Node
+	Token     string // bearer token to authenticate data rpcs
+	// CallTimeout is the deadline of each unary rpc attempt and of each message of streams
+	// 0 means defaultCallTimeout, negative means no deadline
+	CallTimeout time.Duration
+	Pool        PoolConfig
+	Retry       RetryPolicy
+	Breaker     BreakerConfig
+}
+
 type RemoteNodeSenderGrpc struct {
-	connectionPool *cache.Cache
+	connectionPool *connectionPool
+	certReloader   *certReloader
+	identity       ed25519.PrivateKey
+	localNode      *chord.Node
+	token          string
+	callTimeout    time.Duration
+	retryPolicy    RetryPolicy
+	breakers       *circuitBreakers
+	peerVersions   *cache.Cache    // negotiated protocol versions
+	ctx            context.Context // parent of rpc contexts, cancels in-flight rpcs of the operation
 }
 
-func NewRemoteNodeSenderGrpc() chord.RemoteNodeSenderInterface {
-	var remoteSender chord.RemoteNodeSenderInterface
-	remoteSender = &RemoteNodeSenderGrpc{
-		connectionPool: cache.New(10*time.Second, 1*time.Minute),
+func NewRemoteNodeSenderGrpc(config SenderConfig) (chord.RemoteNodeSenderInterface, error) {
+	if config.CallTimeout == 0 {
+		config.CallTimeout = defaultCallTimeout
+	}
+	sender := &RemoteNodeSenderGrpc{
+		identity:     config.Identity,
+		localNode:    config.LocalNode,
+		token:        config.Token,
+		callTimeout:  config.CallTimeout,
+		retryPolicy:  config.Retry.withDefaults(),
+		breakers:     newCircuitBreakers(config.Breaker),
+		peerVersions: cache.New(handshakeCacheTTL, 2*handshakeCacheTTL),
+		ctx:          context.Background(),
+	}
+	sender.connectionPool = newConnectionPool(config.Pool, sender.dialOptions, sender.peerVersions.Delete)
+	if config.TLS != nil {
+		reloader, err := newCertReloader(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		sender.certReloader = reloader
 	}
-	return remoteSender
+	var remoteSender chord.RemoteNodeSenderInterface
+	remoteSender = sender
+	return remoteSender, nil
 }
 
 // FindSuccessor find closest node to the given key in remote node
 // ref D
 func (rs *RemoteNodeSenderGrpc) FindSuccessor(remoteNode *chord.RemoteNode, identifier [helpers.HashSize]byte) (*chord.Node, error) {
-	client := rs.connect(remoteNode)
-	successor, err := client.FindSuccessor(context.Background(), &chordGrpc.Lookup{Key: identifier[:]})
+	var successor *chordGrpc.Node
+	err := rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		successor, err = client.FindSuccessor(rs.ctx, &chordGrpc.Lookup{Key: identifier[:]})
+		return err
+	})
 	if err != nil {
 		log.Errorf("There is no predecessor from: %s:%d - %v - %v\n", remoteNode.IP, remoteNode.Port, successor, err)
-		return nil, err
+		return nil, toLookupError(err)
 	}
 	return chordGrpc.ConvertToChordNode(successor), err
 }
 
+// GetSuccessor remote node's successor
+// successor list is used for legacy peers
+func (rs *RemoteNodeSenderGrpc) GetSuccessor(remoteNode *chord.RemoteNode) (*chord.Node, error) {
+	supported, err := rs.supports(remoteNode, FeatureNeighbors)
+	if err != nil {
+		return nil, err
+	}
+	if !supported {
+		successorList, err := rs.legacySuccessorList(remoteNode)
+		if err != nil {
+			return nil, err
+		}
+		if successorList.Nodes[0] == nil { // single node ring
+			return remoteNode.Node, nil
+		}
+		return successorList.Nodes[0].Node, nil
+	}
+	var successor *chordGrpc.Node
+	err = rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		successor, err = client.GetSuccessor(rs.ctx, &empty.Empty{})
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote GetSuccessor failed: %+v \n", err)
+		return nil, err
+	}
+	return chordGrpc.ConvertToChordNode(successor), nil
+}
+
+// GetPredecessor remote node's predecessor, nil if it's unknown
+func (rs *RemoteNodeSenderGrpc) GetPredecessor(remoteNode *chord.RemoteNode) (*chord.Node, error) {
+	// legacy peers can't return predecessor without side effects
+	if err := rs.require(remoteNode, FeatureNeighbors); err != nil {
+		return nil, err
+	}
+	var predecessor *chordGrpc.Node
+	err := rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		predecessor, err = client.GetPredecessor(rs.ctx, &chordGrpc.Node{})
+		return err
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("Remote GetPredecessor failed: %+v \n", err)
+		return nil, err
+	}
+	return chordGrpc.ConvertToChordNode(predecessor), nil
+}
+
+// GetSuccessorList remote node's successor list
+// ref E.3
+func (rs *RemoteNodeSenderGrpc) GetSuccessorList(remoteNode *chord.RemoteNode) (*chord.SuccessorList, error) {
+	supported, err := rs.supports(remoteNode, FeatureNeighbors)
+	if err != nil {
+		return nil, err
+	}
+	if !supported {
+		return rs.legacySuccessorList(remoteNode)
+	}
+	var nodes *chordGrpc.Nodes
+	err = rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		nodes, err = client.GetSuccessorList(rs.ctx, &empty.Empty{})
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote GetSuccessorList failed: %+v \n", err)
+		return nil, err
+	}
+	return chordGrpc.ConvertToChordSuccessorList(nodes.Nodes, rs), nil
+}
+
+// GetStatus remote node's view on the ring, it's not part of the sender interface
+// since nodes don't need it, only clients use it for introspection
+func (rs *RemoteNodeSenderGrpc) GetStatus(remoteNode *chord.RemoteNode) (*chord.RingStatus, error) {
+	if err := rs.require(remoteNode, FeatureStatus); err != nil {
+		return nil, err
+	}
+	var result *chordGrpc.Status
+	err := rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		result, err = client.GetStatus(rs.ctx, &empty.Empty{})
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote GetStatus failed: %+v \n", err)
+		return nil, err
+	}
+	return chordGrpc.ConvertToChordStatus(result), nil
+}
+
+// Scan returns a page of records ∈ (from, to] of the namespace in remote node, it's not part of the sender interface
+// since nodes don't need it, only clients use it
+func (rs *RemoteNodeSenderGrpc) Scan(remoteNode *chord.RemoteNode, namespace string, from [helpers.HashSize]byte, to [helpers.HashSize]byte, limit int) ([]*chord.Record, error) {
+	if err := rs.require(remoteNode, FeatureScan); err != nil {
+		return nil, err
+	}
+	request := &chordGrpc.ScanRequest{
+		Namespace: namespace,
+		From:      from[:],
+		To:        to[:],
+		Limit:     int32(limit),
+	}
+	var batch *chordGrpc.RecordBatch
+	err := rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		batch, err = client.Scan(rs.ctx, request)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote Scan failed: %+v \n", err)
+		return nil, toChordError(err)
+	}
+	records := make([]*chord.Record, 0, len(batch.Records))
+	for _, data := range batch.Records {
+		record := &chord.Record{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// legacySuccessorList gets successor list of legacy peers using stabilizer data
+// local node is sent as the signed caller, like stabilize does, since peers may verify callers
+// clients don't have a local node, so they send the remote node itself and its predecessor is not replaced
+func (rs *RemoteNodeSenderGrpc) legacySuccessorList(remoteNode *chord.RemoteNode) (*chord.SuccessorList, error) {
+	caller := chordGrpc.ConvertToGrpcNode(remoteNode.Node)
+	if rs.localNode != nil {
+		caller = rs.localNodeClaim(rs.localNode)
+	}
+	var stablizerData *chordGrpc.StablizerData
+	err := rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		stablizerData, err = client.GetStablizerData(rs.ctx, caller)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote GetStablizerData failed: %+v \n", err)
+		return nil, err
+	}
+	return chordGrpc.ConvertToChordSuccessorList(stablizerData.SuccessorList, rs), nil
+}
+
 // GetStablizerData successor's (successor list and predecessor)
 // to prevent duplicate rpc call, we get both together
 // ref E.3
 func (rs *RemoteNodeSenderGrpc) GetStablizerData(remoteNode *chord.RemoteNode, localNode *chord.Node) (*chord.Node, *chord.SuccessorList, error) {
-	// prepare kind of timeout to replace disconnected nodes
-	client := rs.connect(remoteNode)
-
-	stablizerData, err := client.GetStablizerData(context.Background(), chordGrpc.ConvertToGrpcNode(localNode))
+	var stablizerData *chordGrpc.StablizerData
+	claim := rs.localNodeClaim(localNode)
+	err := rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		stablizerData, err = client.GetStablizerData(rs.ctx, claim)
+		return err
+	})
 	if err != nil {
 		log.Errorf("Remote GetStablizerData failed: %+v \n", err)
 		return nil, nil, err
@@ -58,10 +264,14 @@ func (rs *RemoteNodeSenderGrpc) GetStablizerData(remoteNode *chord.RemoteNode, l
 
 // Notify update predecessor
 // is being called periodically by predecessor or new node
+// not retried, next stabilize will notify again
 // ref E.1
 func (rs *RemoteNodeSenderGrpc) Notify(remoteNode *chord.RemoteNode, localNode *chord.Node) error {
-	client := rs.connect(remoteNode) // connect to the successor
-	result, err := client.Notify(context.Background(), chordGrpc.ConvertToGrpcNode(localNode))
+	var result *wrappers.BoolValue
+	err := rs.call(remoteNode, false, func(client chordGrpc.ChordClient) (err error) {
+		result, err = client.Notify(rs.ctx, rs.localNodeClaim(localNode))
+		return err
+	})
 	if err != nil {
 		log.Errorf("Error notifying successor: %s err: %v \n", remoteNode.GetFullAddress(), err)
 		return err
@@ -73,32 +283,113 @@ func (rs *RemoteNodeSenderGrpc) Notify(remoteNode *chord.RemoteNode, localNode *
 	return nil
 }
 
+// Merge asks remote node to place candidate in its ring
+// not retried, merge is repeated by the next probe of members
+func (rs *RemoteNodeSenderGrpc) Merge(remoteNode *chord.RemoteNode, localNode *chord.Node, candidate *chord.Node, hops int) error {
+	if err := rs.require(remoteNode, FeatureMerge); err != nil {
+		return err
+	}
+	request := &chordGrpc.MergeRequest{
+		Caller:    rs.localNodeClaim(localNode),
+		Candidate: chordGrpc.ConvertToGrpcNode(candidate),
+		Hops:      int32(hops),
+	}
+	err := rs.call(remoteNode, false, func(client chordGrpc.ChordClient) (err error) {
+		_, err = client.Merge(rs.ctx, request)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote Merge failed: %+v \n", err)
+		return err
+	}
+	return nil
+}
+
 // Store store data in remote node
-func (rs *RemoteNodeSenderGrpc) Store(remoteNode *chord.RemoteNode, data []byte) bool {
-	client := rs.connect(remoteNode) // connect to the successor
+// not retried, the caller decides based on the error (e.g. owner is changed)
+func (rs *RemoteNodeSenderGrpc) Store(remoteNode *chord.RemoteNode, data []byte) (bool, error) {
 	content := &chordGrpc.Content{
 		Data: data,
 	}
-	result, _ := client.Store(context.Background(), content)
-	return result.Value
+	var result *wrappers.BoolValue
+	err := rs.call(remoteNode, false, func(client chordGrpc.ChordClient) (err error) {
+		result, err = client.Store(rs.ctx, content)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote Store failed: %+v \n", err)
+		return false, toChordError(err)
+	}
+	return result.Value, nil
+}
+
+// StoreReplica push record to remote node as a replica
+// legacy nodes ignore the replica flag and store it like a client record
+func (rs *RemoteNodeSenderGrpc) StoreReplica(remoteNode *chord.RemoteNode, data []byte) (bool, error) {
+	content := &chordGrpc.Content{
+		Data:    data,
+		Replica: true,
+	}
+	if rs.localNode != nil {
+		content.Caller = rs.localNodeClaim(rs.localNode)
+	}
+	var result *wrappers.BoolValue
+	err := rs.call(remoteNode, false, func(client chordGrpc.ChordClient) (err error) {
+		result, err = client.Store(rs.ctx, content)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote StoreReplica failed: %+v \n", err)
+		return false, toChordError(err)
+	}
+	return result.Value, nil
+}
+
+// SetFaultRules replaces fault injection rules of remote node and returns its active rules
+// caller must be an admin of remote node
+func (rs *RemoteNodeSenderGrpc) SetFaultRules(remoteNode *chord.RemoteNode, rules []fault.Rule) ([]fault.Rule, error) {
+	content, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	var result *chordGrpc.FaultRules
+	err = rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		result, err = client.SetFaultRules(rs.ctx, &chordGrpc.FaultRules{Rules: content})
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote SetFaultRules failed: %+v \n", err)
+		return nil, err
+	}
+	return fault.ParseRules(result.Rules)
 }
 
 // Fetch retreive data from remote node
-func (rs *RemoteNodeSenderGrpc) Fetch(remoteNode *chord.RemoteNode, key [helpers.HashSize]byte) []byte {
-	client := rs.connect(remoteNode) // connect to the successor
+func (rs *RemoteNodeSenderGrpc) Fetch(remoteNode *chord.RemoteNode, namespace string, key [helpers.HashSize]byte) ([]byte, error) {
 	lookup := &chordGrpc.Lookup{
-		Key: key[:],
+		Key:       key[:],
+		Namespace: namespace,
+	}
+	var result *chordGrpc.Content
+	err := rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		result, err = client.Fetch(rs.ctx, lookup)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Remote Fetch failed: %+v \n", err)
+		return nil, toChordError(err)
 	}
-	result, _ := client.Fetch(context.Background(), lookup)
-	return result.Data
+	return result.Data, nil
 }
 
 // GetPredecessorList predecessor's (predecessor list)
 func (rs *RemoteNodeSenderGrpc) GetPredecessorList(remoteNode *chord.RemoteNode, localNode *chord.Node) (*chord.PredecessorList, error) {
-	// prepare kind of timeout to replace disconnected nodes
-	client := rs.connect(remoteNode)
-
-	nodeList, err := client.GetPredecessorList(context.Background(), chordGrpc.ConvertToGrpcNode(localNode))
+	var nodeList *chordGrpc.Nodes
+	claim := rs.localNodeClaim(localNode)
+	err := rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		nodeList, err = client.GetPredecessorList(rs.ctx, claim)
+		return err
+	})
 	if err != nil {
 		log.Errorf("Remote GetPredecessorList failed: %+v \n", err)
 		return nil, err
@@ -108,31 +399,144 @@ func (rs *RemoteNodeSenderGrpc) GetPredecessorList(remoteNode *chord.RemoteNode,
 	return predecessorList, nil
 }
 
-// Ping check if remote port is open - using to check predecessor state
-// FIXME should be cached
+// TransferRange receives records ∈ (from, to] of remote node in batches
+// interrupted transfer is resumed after the last received record
+func (rs *RemoteNodeSenderGrpc) TransferRange(remoteNode *chord.RemoteNode, localNode *chord.Node, from [helpers.HashSize]byte, to [helpers.HashSize]byte, receive func(records []*chord.Record) error) error {
+	if err := rs.require(remoteNode, FeatureTransferRange); err != nil {
+		return err
+	}
+	addr := remoteNode.GetFullAddress()
+	request := &chordGrpc.RangeRequest{
+		Caller: rs.localNodeClaim(localNode),
+		From:   from[:],
+		To:     to[:],
+	}
+	var err error
+	for attempt := 0; attempt < rs.retryPolicy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := rs.retryPolicy.backoff(attempt)
+			log.Debugf("sender: resuming range transfer from %s in %v: %v", addr, delay, err)
+			if err := rs.wait(delay); err != nil {
+				return err
+			}
+			// claim might be expired in a long transfer
+			request.Caller = rs.localNodeClaim(localNode)
+		}
+		if err = rs.breakers.Allow(addr); err != nil {
+			return err
+		}
+		var progressed bool
+		progressed, err = rs.transferRange(remoteNode, request, receive)
+		rs.invalidateVersion(remoteNode, err)
+		if err == nil || !retryable(err) {
+			rs.breakers.Success(addr)
+			if err != nil {
+				log.Errorf("Remote TransferRange failed: %+v \n", err)
+			}
+			return toChordError(err)
+		}
+		rs.breakers.Failure(addr)
+		if progressed {
+			attempt = 0 // failures are counted since the last received batch
+		}
+	}
+	log.Errorf("Remote TransferRange failed: %+v \n", err)
+	return err
+}
+
+// transferRange receives batches until the end of stream
+// request.After is moved forward after each received batch
+func (rs *RemoteNodeSenderGrpc) transferRange(remoteNode *chord.RemoteNode, request *chordGrpc.RangeRequest, receive func(records []*chord.Record) error) (bool, error) {
+	client, release, err := rs.connect(remoteNode)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+	ctx, cancel := context.WithCancel(rs.ctx)
+	defer cancel()
+	stream, err := client.TransferRange(ctx, request)
+	if err != nil {
+		return false, err
+	}
+	progressed := false
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			return progressed, nil
+		}
+		if err != nil {
+			return progressed, err
+		}
+		records := make([]*chord.Record, 0, len(batch.Records))
+		for _, data := range batch.Records {
+			record := &chord.Record{}
+			if err := json.Unmarshal(data, record); err != nil {
+				return progressed, err
+			}
+			records = append(records, record)
+		}
+		if len(records) == 0 {
+			continue
+		}
+		if err := receive(records); err != nil {
+			return progressed, err
+		}
+		last := records[len(records)-1]
+		request.After = &chordGrpc.Cursor{
+			Namespace: last.Namespace,
+			Key:       last.Identifier[:],
+		}
+		progressed = true
+	}
+}
+
+// Ping check if remote node is serving using grpc health checking protocol
+// not retried, failure detector relies on the result of each ping
 // ref E.1
 func (rs *RemoteNodeSenderGrpc) Ping(remoteNode *chord.RemoteNode) bool {
-	timeout := time.Second
-	conn, err := net.DialTimeout("tcp", remoteNode.GetFullAddress(), timeout)
+	addr := remoteNode.GetFullAddress()
+	if rs.breakers.Allow(addr) != nil {
+		return false
+	}
+	conn, release, err := rs.connectionPool.Get(addr)
 	if err != nil {
-		log.Errorf("Ping %s:%d error:%v", remoteNode.IP, remoteNode.Port, err)
+		rs.breakers.Failure(addr)
 		return false
 	}
-	if conn != nil {
-		defer conn.Close()
-		return true
+	defer release()
+	client := healthpb.NewHealthClient(conn)
+	ctx, cancel := context.WithTimeout(rs.ctx, pingTimeout)
+	defer cancel()
+	response, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: chordService})
+	if err != nil {
+		log.Errorf("Ping %s:%d error:%v", remoteNode.IP, remoteNode.Port, err)
+		rs.breakers.Failure(addr)
+		return false
 	}
-	return false
+	rs.breakers.Success(addr)
+	return response.Status == healthpb.HealthCheckResponse_SERVING
 }
 
+// GlobalMaintenance compares the digest of the range with remote node
+// data is not synced with older peers, since they don't keep tombstones and creation times in digests
 func (rs *RemoteNodeSenderGrpc) GlobalMaintenance(remoteNode *chord.RemoteNode, data []byte) ([]byte, error) {
-	// prepare kind of timeout to replace disconnected nodes
-	client := rs.connect(remoteNode)
-
+	peer, err := rs.PeerVersion(remoteNode)
+	if err != nil {
+		return nil, err
+	}
+	if peer.Version < syncProtocolVersion {
+		log.Warnf("sender: skipping data sync with %s, peer version %d", remoteNode.GetFullAddress(), peer.Version)
+		return nil, ErrUnsupported
+	}
 	replicationRequest := &chordGrpc.Replication{
-		Data: data,
+		Data:    data,
+		Version: ProtocolVersion,
 	}
-	replicationResponse, err := client.GlobalMaintenance(context.Background(), replicationRequest)
+	var replicationResponse *chordGrpc.Replication
+	err = rs.call(remoteNode, true, func(client chordGrpc.ChordClient) (err error) {
+		replicationResponse, err = client.GlobalMaintenance(rs.ctx, replicationRequest)
+		return err
+	})
 	if err != nil {
 		log.Errorf("Remote GlobalMaintenance failed: %+v \n", err)
 		return nil, err
@@ -140,15 +544,233 @@ func (rs *RemoteNodeSenderGrpc) GlobalMaintenance(remoteNode *chord.RemoteNode,
 	return replicationResponse.Data, nil
 }
 
-// Connect grpc connect to remote node
-func (rs *RemoteNodeSenderGrpc) connect(remoteNode *chord.RemoteNode) chordGrpc.ChordClient {
+// call calls the rpc through the peer's circuit breaker
+// idempotent rpcs are retried with backoff if the peer or network fails
+func (rs *RemoteNodeSenderGrpc) call(remoteNode *chord.RemoteNode, idempotent bool, rpc func(client chordGrpc.ChordClient) error) error {
+	addr := remoteNode.GetFullAddress()
+	attempts := 1
+	if idempotent {
+		attempts = rs.retryPolicy.MaxAttempts
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := rs.retryPolicy.backoff(attempt)
+			log.Debugf("sender: retrying %s in %v: %v", addr, delay, err)
+			if rs.wait(delay) != nil {
+				return err
+			}
+		}
+		if err = rs.breakers.Allow(addr); err != nil {
+			return err
+		}
+		err = rs.attempt(remoteNode, rpc)
+		if err == nil || !retryable(err) {
+			// peer is reachable, even if it rejected the request
+			rs.breakers.Success(addr)
+			return err
+		}
+		rs.breakers.Failure(addr)
+	}
+	return err
+}
+
+// wait sleeps before the next attempt, returns error if the context of the sender is done meanwhile
+func (rs *RemoteNodeSenderGrpc) wait(delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-rs.ctx.Done():
+		return rs.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// attempt calls the rpc once, connection is held until the rpc is done
+func (rs *RemoteNodeSenderGrpc) attempt(remoteNode *chord.RemoteNode, rpc func(client chordGrpc.ChordClient) error) error {
+	client, release, err := rs.connect(remoteNode)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if _, err := rs.peerVersion(remoteNode, client); err != nil {
+		return err
+	}
+	err = rpc(client)
+	rs.invalidateVersion(remoteNode, err)
+	return err
+}
+
+// invalidateVersion removes negotiated version of the peer if it doesn't implement the rpc
+// e.g. the peer is downgraded, so the next call negotiates again
+func (rs *RemoteNodeSenderGrpc) invalidateVersion(remoteNode *chord.RemoteNode, err error) {
+	if status.Code(err) == codes.Unimplemented {
+		rs.peerVersions.Delete(remoteNode.GetFullAddress())
+	}
+}
+
+// PeerVersion returns negotiated protocol version of the remote node
+func (rs *RemoteNodeSenderGrpc) PeerVersion(remoteNode *chord.RemoteNode) (*PeerVersion, error) {
+	client, release, err := rs.connect(remoteNode)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return rs.peerVersion(remoteNode, client)
+}
+
+// peerVersion returns protocol version of the peer, handshake is done once per peer
+// incompatible peers are cached as well, so they are refused without handshake
+func (rs *RemoteNodeSenderGrpc) peerVersion(remoteNode *chord.RemoteNode, client chordGrpc.ChordClient) (*PeerVersion, error) {
 	addr := remoteNode.GetFullAddress()
-	var conn *grpc.ClientConn
-	if x, found := rs.connectionPool.Get(addr); found {
-		conn = x.(*grpc.ClientConn)
+	if x, found := rs.peerVersions.Get(addr); found {
+		peer := x.(*PeerVersion)
+		if !compatible(peer.Version, peer.MinVersion) {
+			return peer, ErrIncompatibleVersion
+		}
+		return peer, nil
+	}
+	peer, err := handshake(rs.ctx, client, addr)
+	if peer != nil {
+		rs.peerVersions.Set(addr, peer, cache.DefaultExpiration)
+	}
+	return peer, err
+}
+
+// supports check if the remote node supports the feature
+func (rs *RemoteNodeSenderGrpc) supports(remoteNode *chord.RemoteNode, feature string) (bool, error) {
+	peer, err := rs.PeerVersion(remoteNode)
+	if err != nil {
+		return false, err
+	}
+	return peer.Supports(feature), nil
+}
+
+// require refuses the operation if the remote node doesn't support the feature
+func (rs *RemoteNodeSenderGrpc) require(remoteNode *chord.RemoteNode, feature string) error {
+	supported, err := rs.supports(remoteNode, feature)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		log.Warnf("sender: %s doesn't support %s", remoteNode.GetFullAddress(), feature)
+		return ErrUnsupported
+	}
+	return nil
+}
+
+// Close closes all pooled connections and stops reloading certificates
+func (rs *RemoteNodeSenderGrpc) Close() {
+	rs.connectionPool.Close()
+	if rs.certReloader != nil {
+		rs.certReloader.Close()
+	}
+}
+
+// WithContext returns a sender sharing connections and peer states, whose rpcs are bound to ctx
+// retries stop and in-flight rpcs are cancelled when ctx is done
+func (rs *RemoteNodeSenderGrpc) WithContext(ctx context.Context) *RemoteNodeSenderGrpc {
+	sender := *rs
+	sender.ctx = ctx
+	return &sender
+}
+
+// Connect grpc connect to remote node using pooled connection
+// release must be called when the call is done
+func (rs *RemoteNodeSenderGrpc) connect(remoteNode *chord.RemoteNode) (chordGrpc.ChordClient, func(), error) {
+	conn, release, err := rs.connectionPool.Get(remoteNode.GetFullAddress())
+	if err != nil {
+		return nil, nil, err
+	}
+	return chordGrpc.NewChordClient(conn), release, nil
+}
+
+// dialOptions returns transport security options using the latest certificates
+func (rs *RemoteNodeSenderGrpc) dialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if rs.certReloader == nil {
+		opts = append(opts, grpc.WithInsecure())
 	} else {
-		conn, _ = grpc.Dial(addr, grpc.WithInsecure())
-		rs.connectionPool.Set(addr, conn, cache.DefaultExpiration)
+		opts = append(opts, grpc.WithTransportCredentials(rs.certReloader.clientCredentials()))
+	}
+	if rs.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{token: rs.token}))
+	}
+	if rs.callTimeout > 0 {
+		opts = append(opts,
+			grpc.WithUnaryInterceptor(deadlineInterceptor(rs.callTimeout)),
+			grpc.WithStreamInterceptor(idleStreamInterceptor(rs.callTimeout)),
+		)
+	}
+	return opts
+}
+
+// deadlineInterceptor sets the deadline of each unary rpc, an earlier deadline of the caller is kept
+func deadlineInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// idleStreamInterceptor cancels streams which don't receive the next message within timeout
+// the whole stream is not limited, since range transfers can take long
+func idleStreamInterceptor(timeout time.Duration) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		stream := &idleStream{timeout: timeout, cancel: cancel}
+		stream.timer = time.AfterFunc(timeout, stream.expire)
+		clientStream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			stream.timer.Stop()
+			cancel()
+			return nil, stream.convert(err)
+		}
+		stream.ClientStream = clientStream
+		return stream, nil
+	}
+}
+
+// idleStream is a client stream which is canceled if it's idle
+// time between receiving a message and waiting for the next one is not counted
+type idleStream struct {
+	grpc.ClientStream
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired int32
+}
+
+func (s *idleStream) RecvMsg(m interface{}) error {
+	s.timer.Reset(s.timeout)
+	err := s.ClientStream.RecvMsg(m)
+	s.timer.Stop()
+	if err != nil {
+		s.cancel()
+	}
+	return s.convert(err)
+}
+
+func (s *idleStream) expire() {
+	atomic.StoreInt32(&s.expired, 1)
+	s.cancel()
+}
+
+// convert reports canceled streams as deadline exceeded, so they are retried
+func (s *idleStream) convert(err error) error {
+	if err != nil && atomic.LoadInt32(&s.expired) == 1 {
+		return status.Errorf(codes.DeadlineExceeded, "stream is idle for %v", s.timeout)
+	}
+	return err
+}
+
+// localNodeClaim converts local node to grpc node, signed by identity key if it's set
+func (rs *RemoteNodeSenderGrpc) localNodeClaim(localNode *chord.Node) *chordGrpc.Node {
+	node := chordGrpc.ConvertToGrpcNode(localNode)
+	if rs.identity != nil {
+		signNode(node, rs.identity)
 	}
-	return chordGrpc.NewChordClient(conn)
+	return node
 }
@@ -0,0 +1,184 @@
+package net
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/helpers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rangeRing serves records and fetches through a real grpc receiver
+// fetchErrors and transferErrors are returned by the first calls, transfers fail after failAfter batches
+type rangeRing struct {
+	chord.RingInterface
+	mutex          sync.Mutex
+	node           *chord.Node
+	records        []*chord.Record
+	fetchErrors    []error
+	fetches        int
+	transferErrors []error
+	failAfter      int
+	cursors        []*chord.RangeCursor // cursor of each transfer
+}
+
+func (r *rangeRing) GetLocalNode() *chord.Node {
+	return r.node
+}
+
+func (r *rangeRing) IsReady() bool {
+	return true
+}
+
+func (r *rangeRing) Fetch(namespace string, key [helpers.HashSize]byte) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fetches++
+	if len(r.fetchErrors) > 0 {
+		err := r.fetchErrors[0]
+		r.fetchErrors = r.fetchErrors[1:]
+		return nil, err
+	}
+	return []byte("value"), nil
+}
+
+func (r *rangeRing) TransferRange(from [helpers.HashSize]byte, to [helpers.HashSize]byte, after *chord.RangeCursor, send func(records []*chord.Record) error) error {
+	r.mutex.Lock()
+	r.cursors = append(r.cursors, after)
+	var failure error
+	if len(r.transferErrors) > 0 {
+		failure = r.transferErrors[0]
+		r.transferErrors = r.transferErrors[1:]
+	}
+	r.mutex.Unlock()
+	start := 0
+	if after != nil {
+		for i, record := range r.records {
+			if record.Identifier == after.Key {
+				start = i + 1
+			}
+		}
+	}
+	for i, record := range r.records[start:] {
+		if failure != nil && i == r.failAfter {
+			return failure
+		}
+		if err := send([]*chord.Record{record}); err != nil {
+			return err
+		}
+	}
+	return failure
+}
+
+// startReceiver serves the ring on a free local port, returns its remote node and a sender with short backoffs
+func startReceiver(t *testing.T, ring *rangeRing) (*chord.RemoteNode, *RemoteNodeSenderGrpc) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	ring.node = chord.NewNode("127.0.0.1", uint(port))
+	receiver, err := NewChordReceiver(ring, ReceiverConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(receiver.Close)
+	sender, err := NewRemoteNodeSenderGrpc(SenderConfig{
+		LocalNode: chord.NewNode("127.0.0.1", 1),
+		Retry:     RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcSender := sender.(*RemoteNodeSenderGrpc)
+	t.Cleanup(grpcSender.Close)
+	return chord.NewRemoteNode(ring.node, sender), grpcSender
+}
+
+// testRecords returns records of a namespace in the order they are transferred
+func testRecords(keys ...string) []*chord.Record {
+	records := make([]*chord.Record, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, &chord.Record{
+			Namespace:  "test",
+			Key:        key,
+			Identifier: helpers.Hash(key),
+			Content:    []byte(key),
+		})
+	}
+	return records
+}
+
+func TestTransferRangeResumesAfterLastBatch(t *testing.T) {
+	ring := &rangeRing{
+		records:        testRecords("a", "b", "c", "d"),
+		transferErrors: []error{status.Error(codes.Unavailable, "connection reset")},
+		failAfter:      2,
+	}
+	remoteNode, sender := startReceiver(t, ring)
+	var received []string
+	err := sender.TransferRange(remoteNode, chord.NewNode("127.0.0.1", 1), [helpers.HashSize]byte{}, [helpers.HashSize]byte{}, func(records []*chord.Record) error {
+		for _, record := range records {
+			received = append(received, record.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if len(received) != 4 || received[0] != "a" || received[1] != "b" || received[2] != "c" || received[3] != "d" {
+		t.Fatalf("received %v, expected [a b c d]", received)
+	}
+	if len(ring.cursors) != 2 || ring.cursors[0] != nil {
+		t.Fatalf("expected one transfer from the start and one resumed, got cursors %v", ring.cursors)
+	}
+	if resumed := ring.cursors[1]; resumed.Namespace != "test" || resumed.Key != helpers.Hash("b") {
+		t.Errorf("transfer is resumed after %s:%x, expected after the last received record", resumed.Namespace, resumed.Key)
+	}
+}
+
+func TestTransferRangeDoesNotResumeRejectedTransfer(t *testing.T) {
+	ring := &rangeRing{
+		records:        testRecords("a", "b"),
+		transferErrors: []error{status.Error(codes.PermissionDenied, "denied")},
+		failAfter:      1,
+	}
+	remoteNode, sender := startReceiver(t, ring)
+	err := sender.TransferRange(remoteNode, chord.NewNode("127.0.0.1", 1), [helpers.HashSize]byte{}, [helpers.HashSize]byte{}, func(records []*chord.Record) error {
+		return nil
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	if len(ring.cursors) != 1 {
+		t.Errorf("rejected transfer is resumed %d times", len(ring.cursors)-1)
+	}
+}
+
+func TestTransferRangeStopsWhenContextIsDone(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+	ring := &rangeRing{
+		records:        testRecords("a", "b"),
+		transferErrors: []error{unavailable, unavailable, unavailable},
+	}
+	remoteNode, sender := startReceiver(t, ring)
+	sender.retryPolicy = RetryPolicy{InitialBackoff: time.Hour}.withDefaults()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err := sender.WithContext(ctx).TransferRange(remoteNode, chord.NewNode("127.0.0.1", 1), [helpers.HashSize]byte{}, [helpers.HashSize]byte{}, func(records []*chord.Record) error {
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("transfer returned %v after the context is done", elapsed)
+	}
+}
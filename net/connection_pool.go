@@ -0,0 +1,210 @@
+package net
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+)
+
+// default connection pool settings
+const (
+	defaultIdleTimeout      time.Duration = time.Minute
+	defaultKeepaliveTime    time.Duration = 30 * time.Second
+	defaultKeepaliveTimeout time.Duration = 5 * time.Second
+	// keepaliveMinTime is the minimum keepalive interval accepted by receiver
+	// grpc clients don't ping more frequent than 10s anyway
+	keepaliveMinTime time.Duration = 10 * time.Second
+)
+
+// ErrPoolExhausted is returned when max connections are open and all of them are in use
+var ErrPoolExhausted = errors.New("connection pool is exhausted")
+
+// PoolConfig connection pool configuration
+type PoolConfig struct {
+	// IdleTimeout closes connections which are not used for this long
+	IdleTimeout time.Duration
+	// MaxConnections limits the number of open connections, 0 means unlimited
+	// least recently used connection is closed to open a new one
+	MaxConnections int
+	// Keepalive is used for all peers unless it's overridden in PeerKeepalive
+	Keepalive keepalive.ClientParameters
+	// PeerKeepalive keepalive settings per peer address (ip:port)
+	PeerKeepalive map[string]keepalive.ClientParameters
+}
+
+// connectionPool keeps one grpc connection per peer
+// connections are closed when they are evicted and not used by any call
+type connectionPool struct {
+	mutex       sync.Mutex
+	connections *cache.Cache
+	config      PoolConfig
+	dialOptions func() []grpc.DialOption
+	// onReconnect is called when the connection of the address is dialed again or reset
+	// the peer might be restarted with another version
+	onReconnect func(addr string)
+}
+
+// pooledConnection counts the calls using the connection
+// evicted connection is closed by the last call which releases it
+type pooledConnection struct {
+	mutex   sync.Mutex
+	conn    *grpc.ClientConn
+	users   int
+	evicted bool
+}
+
+func (c *pooledConnection) acquire() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.users++
+}
+
+func (c *pooledConnection) release() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.users--
+	if c.users == 0 && c.evicted {
+		c.conn.Close()
+	}
+}
+
+// evict closes the connection if it's not in use, otherwise the last user closes it
+func (c *pooledConnection) evict() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evicted = true
+	if c.users == 0 {
+		c.conn.Close()
+	}
+}
+
+func (c *pooledConnection) busy() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.users > 0
+}
+
+func newConnectionPool(config PoolConfig, dialOptions func() []grpc.DialOption, onReconnect func(addr string)) *connectionPool {
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = defaultIdleTimeout
+	}
+	if config.Keepalive == (keepalive.ClientParameters{}) {
+		config.Keepalive = keepalive.ClientParameters{
+			Time:                defaultKeepaliveTime,
+			Timeout:             defaultKeepaliveTimeout,
+			PermitWithoutStream: true, // idle connections are checked as well
+		}
+	}
+	pool := &connectionPool{
+		connections: cache.New(config.IdleTimeout, config.IdleTimeout/2),
+		config:      config,
+		dialOptions: dialOptions,
+		onReconnect: onReconnect,
+	}
+	pool.connections.OnEvicted(func(addr string, x interface{}) {
+		log.Debugf("pool: closing connection to %s", addr)
+		x.(*pooledConnection).evict()
+	})
+	return pool
+}
+
+// Get returns pooled connection of the address or dials a new one
+// release must be called when the call is done, so the connection is not closed while it's in use
+// connections in transient failure are reconnected immediately
+// instead of waiting for grpc backoff
+func (p *connectionPool) Get(addr string) (*grpc.ClientConn, func(), error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if x, found := p.connections.Get(addr); found {
+		pooled := x.(*pooledConnection)
+		switch pooled.conn.GetState() {
+		case connectivity.Shutdown:
+			p.connections.Delete(addr)
+		case connectivity.TransientFailure:
+			log.Debugf("pool: connection to %s is in transient failure, reconnecting", addr)
+			pooled.conn.ResetConnectBackoff()
+			p.onReconnect(addr)
+			p.touch(addr, pooled)
+			pooled.acquire()
+			return pooled.conn, pooled.release, nil
+		default:
+			p.touch(addr, pooled)
+			pooled.acquire()
+			return pooled.conn, pooled.release, nil
+		}
+	}
+	// expired connections are not returned by cache but they are still open
+	p.connections.DeleteExpired()
+	if p.config.MaxConnections > 0 && p.connections.ItemCount() >= p.config.MaxConnections {
+		if !p.evictLeastRecentlyUsed() {
+			return nil, nil, ErrPoolExhausted
+		}
+	}
+	opts := append(p.dialOptions(), grpc.WithKeepaliveParams(p.keepalive(addr)))
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
+		log.Errorf("pool: dial %s failed: %v", addr, err)
+		return nil, nil, err
+	}
+	p.onReconnect(addr)
+	pooled := &pooledConnection{conn: conn, users: 1}
+	p.connections.Set(addr, pooled, cache.DefaultExpiration)
+	return conn, pooled.release, nil
+}
+
+// Remove closes the connection of the address, after the calls using it are done
+func (p *connectionPool) Remove(addr string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.connections.Delete(addr)
+}
+
+// Close closes all connections
+func (p *connectionPool) Close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for addr := range p.connections.Items() {
+		p.connections.Delete(addr)
+	}
+}
+
+// touch extends expiration of the connection, it doesn't trigger eviction
+func (p *connectionPool) touch(addr string, pooled *pooledConnection) {
+	p.connections.Set(addr, pooled, cache.DefaultExpiration)
+}
+
+// evictLeastRecentlyUsed closes the idle connection which expires first
+// since expiration is extended on every use
+// connections in use are skipped, so max connections is not exceeded by deferred closes
+func (p *connectionPool) evictLeastRecentlyUsed() bool {
+	var oldest string
+	var oldestExpiration int64
+	for addr, item := range p.connections.Items() {
+		if item.Object.(*pooledConnection).busy() {
+			continue
+		}
+		if oldest == "" || item.Expiration < oldestExpiration {
+			oldest = addr
+			oldestExpiration = item.Expiration
+		}
+	}
+	if oldest == "" {
+		return false
+	}
+	p.connections.Delete(oldest)
+	return true
+}
+
+// keepalive returns keepalive settings of the peer
+func (p *connectionPool) keepalive(addr string) keepalive.ClientParameters {
+	if params, ok := p.config.PeerKeepalive[addr]; ok {
+		return params
+	}
+	return p.config.Keepalive
+}
@@ -0,0 +1,70 @@
+package net
+
+import (
+	"github.com/mbrostami/chord"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// signatureField is the field of the bad request detail of invalid record signatures
+// other invalid arguments are not converted to chord.ErrInvalidSignature
+const signatureField string = "record.signature"
+
+// toGrpcError converts chord errors to grpc status errors
+func toGrpcError(err error) error {
+	switch err {
+	case chord.ErrNotOwner:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case chord.ErrEncryptedRecord, chord.ErrInvalidRecord:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case chord.ErrInvalidSignature:
+		s, detailErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: signatureField, Description: err.Error()}},
+		})
+		if detailErr != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return s.Err()
+	}
+	return err
+}
+
+// toChordError converts grpc status errors to chord errors
+func toChordError(err error) error {
+	switch status.Code(err) {
+	case codes.FailedPrecondition:
+		return chord.ErrNotOwner
+	case codes.InvalidArgument:
+		if isSignatureViolation(err) {
+			return chord.ErrInvalidSignature
+		}
+	}
+	return err
+}
+
+// isSignatureViolation check if the status error has the bad request detail of invalid signatures
+func isSignatureViolation(err error) bool {
+	for _, detail := range status.Convert(err).Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, violation := range badRequest.FieldViolations {
+			if violation.Field == signatureField {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toLookupError converts the error of a lookup hop
+// errors returned by the remote node mean it's alive but the next hops failed
+// legacy nodes return unknown errors in that case
+func toLookupError(err error) error {
+	if s, ok := status.FromError(err); ok && (s.Code() == codes.NotFound || s.Code() == codes.Unknown) {
+		return chord.ErrLookupFailed
+	}
+	return err
+}
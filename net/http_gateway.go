@@ -0,0 +1,350 @@
+package net
+
+import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/helpers"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxValueSize is the maximum size of values stored through the gateway
+const maxValueSize int64 = 1 << 20
+
+// ownerAttempts is the number of owner lookups of a key, cached owner is replaced after ErrNotOwner
+const ownerAttempts int = 2
+
+// errOwnerUnavailable is returned when the owner of the key can't be found
+var errOwnerUnavailable = status.Error(codes.Unavailable, "owner of the key is not available")
+
+// GatewayConfig http gateway configuration
+type GatewayConfig struct {
+	Address string     // listen address, e.g. 127.0.0.1:8080
+	TLS     *TLSConfig // nil means plain http
+	// Authenticator authenticates clients of key-value endpoints, nil means no authentication
+	Authenticator *Authenticator
+	// AccessControl authorizes authenticated clients per namespace, nil means no authorization
+	AccessControl *AccessControl
+	// Identity signs stored records if it's set, its public key must be a trusted writer of the nodes
+	Identity ed25519.PrivateKey
+}
+
+// HTTPGateway serves key-value operations and ring introspection as json over http
+// requests are routed through the ring like rpcs of the grpc receiver
+type HTTPGateway struct {
+	ring   chord.RingInterface
+	config GatewayConfig
+	server *http.Server
+	// certReloader reloads the server certificate, nil means plain http
+	certReloader *certReloader
+}
+
+// NewHTTPGateway starts http server in background
+// returns when the server is listening
+func NewHTTPGateway(ring chord.RingInterface, config GatewayConfig) (*HTTPGateway, error) {
+	gateway := &HTTPGateway{
+		ring:   ring,
+		config: config,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kv/", gateway.handleKey)
+	mux.HandleFunc("/ring/successors", gateway.handleSuccessors)
+	mux.HandleFunc("/ring/fingers", gateway.handleFingers)
+	gateway.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, err
+	}
+	if config.TLS != nil {
+		if config.TLS.CertFile == "" {
+			listener.Close()
+			return nil, errors.New("tls: server certificate is required")
+		}
+		reloader, err := newCertReloader(config.TLS)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("tls: %v", err)
+		}
+		gateway.certReloader = reloader
+		listener = tls.NewListener(listener, reloader.serverConfig())
+	}
+	log.Infof("Start listening on http gateway: %s\n", config.Address)
+	go gateway.server.Serve(listener)
+	return gateway, nil
+}
+
+// Close stops the http server and reloading its certificate
+func (g *HTTPGateway) Close() error {
+	if g.certReloader != nil {
+		g.certReloader.Close()
+	}
+	return g.server.Close()
+}
+
+type gatewayNode struct {
+	Address    string `json:"address"`
+	Identifier string `json:"identifier"`
+}
+
+func newGatewayNode(node *chord.Node) *gatewayNode {
+	if node == nil {
+		return nil
+	}
+	return &gatewayNode{
+		Address:    node.GetFullAddress(),
+		Identifier: hex.EncodeToString(node.Identifier[:]),
+	}
+}
+
+type gatewayRecord struct {
+	Key          string     `json:"key"`
+	Namespace    string     `json:"namespace,omitempty"`
+	Identifier   string     `json:"identifier"`
+	Value        *string    `json:"value,omitempty"`
+	CreationTime *time.Time `json:"creation_time,omitempty"`
+	Encrypted    bool       `json:"encrypted,omitempty"` // the node doesn't have the encryption key
+	Deleted      bool       `json:"deleted,omitempty"`
+}
+
+// handleKey serves GET, PUT and DELETE of /kv/{key}, namespace is taken from the query
+func (g *HTTPGateway) handleKey(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/kv/")
+	if key == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "key is required"))
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	if !g.ring.IsReady() {
+		writeError(w, status.Error(codes.Unavailable, "node is not in a ring"))
+		return
+	}
+	identity, err := g.authenticate(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	identifier := chord.RecordIdentifier(namespace, key)
+	output := &gatewayRecord{
+		Key:        key,
+		Namespace:  namespace,
+		Identifier: hex.EncodeToString(identifier[:]),
+	}
+	switch r.Method {
+	case http.MethodGet:
+		record, err := g.fetch(identity, namespace, identifier)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		value := string(record.Content)
+		output.Value = &value
+		output.CreationTime = &record.CreationTime
+		output.Encrypted = record.IsEncrypted()
+	case http.MethodPut:
+		value, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxValueSize))
+		if err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "value is too large"))
+			return
+		}
+		record := &chord.Record{
+			CreationTime: time.Now(),
+			Content:      value,
+			Identifier:   identifier,
+			Namespace:    namespace,
+			Key:          key,
+		}
+		if err := g.store(identity, record); err != nil {
+			writeError(w, err)
+			return
+		}
+	case http.MethodDelete:
+		if err := g.store(identity, chord.NewTombstone(namespace, key)); err != nil {
+			writeError(w, err)
+			return
+		}
+		output.Deleted = true
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method is not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+// handleSuccessors serves successor, predecessor and successor list of the node
+func (g *HTTPGateway) handleSuccessors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method is not allowed"})
+		return
+	}
+	ringStatus := g.ring.Status()
+	output := struct {
+		Node          *gatewayNode   `json:"node"`
+		Successor     *gatewayNode   `json:"successor"`
+		Predecessor   *gatewayNode   `json:"predecessor"`
+		SuccessorList []*gatewayNode `json:"successor_list"`
+	}{
+		Node:          newGatewayNode(ringStatus.Node),
+		Successor:     newGatewayNode(ringStatus.Successor),
+		Predecessor:   newGatewayNode(ringStatus.Predecessor),
+		SuccessorList: []*gatewayNode{},
+	}
+	for _, node := range ringStatus.SuccessorList {
+		output.SuccessorList = append(output.SuccessorList, newGatewayNode(node))
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+type gatewayFinger struct {
+	Index int          `json:"index"`
+	Node  *gatewayNode `json:"node"`
+}
+
+// handleFingers serves distinct fingers of the node ordered by their index
+func (g *HTTPGateway) handleFingers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method is not allowed"})
+		return
+	}
+	ringStatus := g.ring.Status()
+	output := struct {
+		Node    *gatewayNode     `json:"node"`
+		Fingers []*gatewayFinger `json:"fingers"`
+	}{
+		Node:    newGatewayNode(ringStatus.Node),
+		Fingers: []*gatewayFinger{},
+	}
+	for index, node := range ringStatus.Fingers {
+		output.Fingers = append(output.Fingers, &gatewayFinger{Index: index, Node: newGatewayNode(node)})
+	}
+	sort.Slice(output.Fingers, func(i, j int) bool {
+		return output.Fingers[i].Index < output.Fingers[j].Index
+	})
+	writeJSON(w, http.StatusOK, output)
+}
+
+// authenticate returns the client identity, empty if authentication is disabled
+func (g *HTTPGateway) authenticate(r *http.Request) (string, error) {
+	if g.config.Authenticator == nil {
+		return "", nil
+	}
+	identity, err := g.config.Authenticator.AuthenticateHTTP(r)
+	if err != nil {
+		log.Warnf("gateway: %s %s rejected: %v", r.Method, r.URL.Path, err)
+	}
+	return identity, err
+}
+
+// store authorizes the client and stores the record in its owner
+// record is signed by the node identity, since clients of the gateway don't sign records
+func (g *HTTPGateway) store(identity string, record *chord.Record) error {
+	if g.config.AccessControl != nil {
+		if err := g.config.AccessControl.AuthorizeRecord(identity, record); err != nil {
+			return err
+		}
+	}
+	if g.config.Identity != nil {
+		record.Sign(g.config.Identity)
+	}
+	data := record.GetJson()
+	for attempt := 0; attempt < ownerAttempts; attempt++ {
+		owner := g.ring.FindSuccessor(record.Identifier)
+		if owner == nil {
+			return errOwnerUnavailable
+		}
+		var err error
+		if owner.Identifier == g.ring.GetLocalNode().Identifier {
+			_, err = g.ring.Store(data)
+		} else {
+			_, err = owner.Store(data)
+		}
+		if err != chord.ErrNotOwner {
+			return err
+		}
+		g.ring.InvalidateLocation(record.Identifier)
+	}
+	return chord.ErrNotOwner
+}
+
+// fetch authorizes the client and fetches the record from its owner
+// returns NotFound if the key doesn't exist or it's deleted
+func (g *HTTPGateway) fetch(identity string, namespace string, identifier [helpers.HashSize]byte) (*chord.Record, error) {
+	if g.config.AccessControl != nil {
+		if err := g.config.AccessControl.Authorize(identity, namespace, PermissionRead); err != nil {
+			return nil, err
+		}
+	}
+	for attempt := 0; attempt < ownerAttempts; attempt++ {
+		owner := g.ring.FindSuccessor(identifier)
+		if owner == nil {
+			return nil, errOwnerUnavailable
+		}
+		var data []byte
+		var err error
+		if owner.Identifier == g.ring.GetLocalNode().Identifier {
+			data, err = g.ring.Fetch(namespace, identifier)
+		} else {
+			data, err = owner.Fetch(namespace, identifier)
+		}
+		if err == chord.ErrNotOwner {
+			g.ring.InvalidateLocation(identifier)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := &chord.Record{}
+		if data == nil || json.Unmarshal(data, record) != nil || record.Deleted {
+			return nil, status.Error(codes.NotFound, "key is not found")
+		}
+		return record, nil
+	}
+	return nil, chord.ErrNotOwner
+}
+
+// writeError writes the error as json with the http status of its grpc code
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch status.Code(toGrpcError(err)) {
+	case codes.InvalidArgument:
+		code = http.StatusBadRequest
+	case codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.Unavailable, codes.FailedPrecondition:
+		code = http.StatusServiceUnavailable
+	}
+	if s, ok := status.FromError(err); ok {
+		err = errors.New(s.Message())
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
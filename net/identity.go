@@ -0,0 +1,81 @@
+package net
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/mbrostami/chord"
+	chordGrpc "github.com/mbrostami/chord/grpc"
+)
+
+// signatureWindow is the maximum accepted age of a signed node claim
+const signatureWindow time.Duration = time.Minute
+
+// LoadIdentity loads ed25519 private key of the node from PEM file
+// a new key is generated and stored if file doesn't exist
+func LoadIdentity(file string) (ed25519.PrivateKey, error) {
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		return privateKey, ioutil.WriteFile(file, block, 0600)
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("identity: no PEM block found in " + file)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("identity: key is not ed25519")
+	}
+	return privateKey, nil
+}
+
+// signNode signs the node claim, so receiver can verify the caller owns the public key
+func signNode(node *chordGrpc.Node, privateKey ed25519.PrivateKey) {
+	node.PublicKey = privateKey.Public().(ed25519.PublicKey)
+	node.Timestamp = time.Now().Unix()
+	node.Signature = ed25519.Sign(privateKey, chordGrpc.NodeClaim(node))
+}
+
+// SignNode signs the claim of the local node
+// the claim is relayed to other nodes with the node, so they derive the same identifier from its public key
+func SignNode(node *chord.Node, privateKey ed25519.PrivateKey) {
+	claim := chordGrpc.ConvertToGrpcNode(node)
+	signNode(claim, privateKey)
+	node.PublicKey = claim.PublicKey
+	node.Signature = claim.Signature
+	node.Timestamp = claim.Timestamp
+}
+
+// verifyNodeSignature check the node claim is signed by its public key and it's not expired
+func verifyNodeSignature(node *chordGrpc.Node) error {
+	if err := chordGrpc.VerifyNodeClaim(node); err != nil {
+		return err
+	}
+	age := time.Since(time.Unix(node.Timestamp, 0))
+	if age > signatureWindow || age < -signatureWindow {
+		return errors.New("node claim is expired")
+	}
+	return nil
+}
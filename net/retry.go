@@ -0,0 +1,168 @@
+package net
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// default retry and circuit breaker settings
+const (
+	defaultMaxAttempts      int           = 3
+	defaultInitialBackoff   time.Duration = 50 * time.Millisecond
+	defaultMaxBackoff       time.Duration = time.Second
+	defaultBackoffFactor    float64       = 2
+	defaultFailureThreshold int           = 5
+	defaultOpenTimeout      time.Duration = 10 * time.Second
+)
+
+// ErrCircuitOpen is returned without calling the peer when it keeps failing
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// RetryPolicy retry configuration of idempotent rpcs
+type RetryPolicy struct {
+	MaxAttempts    int // including the first call
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// BreakerConfig circuit breaker configuration
+type BreakerConfig struct {
+	// FailureThreshold consecutive failures to open the circuit of a peer
+	FailureThreshold int
+	// OpenTimeout is the duration calls are short-circuited before a probe call is allowed
+	OpenTimeout time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = defaultBackoffFactor
+	}
+	return p
+}
+
+// backoff returns jittered delay before the given retry
+// delay is uniformly chosen in [d/2, d) where d grows exponentially
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		delay *= p.Multiplier
+		if delay > float64(p.MaxBackoff) {
+			delay = float64(p.MaxBackoff)
+			break
+		}
+	}
+	return time.Duration(delay/2 + rand.Float64()*delay/2)
+}
+
+// retryable check if the error is caused by the peer or network, not by the request
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// breakerState state of a peer's circuit
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type circuitBreaker struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+// circuitBreakers keeps circuit breaker of failing peers
+// peers are removed as soon as a call succeeds
+type circuitBreakers struct {
+	mutex  sync.Mutex
+	config BreakerConfig
+	peers  map[string]*circuitBreaker
+}
+
+func newCircuitBreakers(config BreakerConfig) *circuitBreakers {
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = defaultFailureThreshold
+	}
+	if config.OpenTimeout == 0 {
+		config.OpenTimeout = defaultOpenTimeout
+	}
+	return &circuitBreakers{
+		config: config,
+		peers:  make(map[string]*circuitBreaker),
+	}
+}
+
+// Allow check if a call to the peer is allowed
+// after open timeout only one probe call is allowed until its result is reported
+func (c *circuitBreakers) Allow(addr string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	breaker, ok := c.peers[addr]
+	if !ok {
+		return nil
+	}
+	switch breaker.state {
+	case breakerOpen:
+		if time.Since(breaker.openedAt) < c.config.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		breaker.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// Success closes the circuit of the peer
+func (c *circuitBreakers) Success(addr string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if breaker, ok := c.peers[addr]; ok && breaker.state != breakerClosed {
+		log.Infof("breaker: circuit of %s is closed", addr)
+	}
+	delete(c.peers, addr)
+}
+
+// Failure records a failed call, circuit is opened after consecutive failures
+// or if the probe call fails
+func (c *circuitBreakers) Failure(addr string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	breaker, ok := c.peers[addr]
+	if !ok {
+		breaker = &circuitBreaker{}
+		c.peers[addr] = breaker
+	}
+	breaker.failures++
+	if breaker.state == breakerHalfOpen || breaker.failures >= c.config.FailureThreshold {
+		if breaker.state != breakerOpen {
+			log.Warnf("breaker: circuit of %s is open after %d failures", addr, breaker.failures)
+		}
+		breaker.state = breakerOpen
+		breaker.openedAt = time.Now()
+	}
+}
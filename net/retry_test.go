@@ -0,0 +1,125 @@
+package net
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mbrostami/chord/helpers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoffGrowsUpToMaxBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}.withDefaults()
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	for i, delay := range expected {
+		retry := i + 1
+		for j := 0; j < 100; j++ {
+			backoff := policy.backoff(retry)
+			if backoff < delay/2 || backoff >= delay {
+				t.Fatalf("backoff of retry %d is %v, expected in [%v, %v)", retry, backoff, delay/2, delay)
+			}
+		}
+	}
+}
+
+func TestRetryableErrors(t *testing.T) {
+	for _, code := range []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted} {
+		if !retryable(status.Error(code, "")) {
+			t.Errorf("%v is not retried", code)
+		}
+	}
+	for _, err := range []error{
+		status.Error(codes.InvalidArgument, ""),
+		status.Error(codes.PermissionDenied, ""),
+		status.Error(codes.FailedPrecondition, ""),
+		errors.New("not a status"),
+	} {
+		if retryable(err) {
+			t.Errorf("%v is retried", err)
+		}
+	}
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	breakers := newCircuitBreakers(BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Hour})
+	addr := "10.0.0.1:10001"
+	for i := 0; i < 2; i++ {
+		breakers.Failure(addr)
+		if err := breakers.Allow(addr); err != nil {
+			t.Fatalf("circuit is open after %d failures", i+1)
+		}
+	}
+	breakers.Failure(addr)
+	if err := breakers.Allow(addr); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if err := breakers.Allow("10.0.0.2:10001"); err != nil {
+		t.Errorf("circuit of another peer is open")
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	breakers := newCircuitBreakers(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour})
+	addr := "10.0.0.1:10001"
+	breakers.Failure(addr)
+	breakers.Success(addr)
+	breakers.Failure(addr)
+	if err := breakers.Allow(addr); err != nil {
+		t.Errorf("failures before a success are counted: %v", err)
+	}
+}
+
+func TestBreakerAllowsOneProbeAfterOpenTimeout(t *testing.T) {
+	breakers := newCircuitBreakers(BreakerConfig{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond})
+	addr := "10.0.0.1:10001"
+	breakers.Failure(addr)
+	time.Sleep(20 * time.Millisecond)
+	if err := breakers.Allow(addr); err != nil {
+		t.Fatalf("probe call is refused: %v", err)
+	}
+	if err := breakers.Allow(addr); err != ErrCircuitOpen {
+		t.Fatalf("second call during the probe is allowed: %v", err)
+	}
+	// failed probe opens the circuit again
+	breakers.Failure(addr)
+	if err := breakers.Allow(addr); err != ErrCircuitOpen {
+		t.Fatalf("circuit is not opened after the probe failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	breakers.Allow(addr)
+	breakers.Success(addr)
+	if err := breakers.Allow(addr); err != nil {
+		t.Errorf("circuit is not closed after the probe succeeded: %v", err)
+	}
+}
+
+func TestFetchRetriesUnavailablePeer(t *testing.T) {
+	ring := &rangeRing{fetchErrors: []error{status.Error(codes.Unavailable, "overloaded")}}
+	remoteNode, sender := startReceiver(t, ring)
+	data, err := sender.Fetch(remoteNode, "test", helpers.Hash("key"))
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if string(data) != "value" {
+		t.Errorf("fetched %q, expected %q", data, "value")
+	}
+	if ring.fetches != 2 {
+		t.Errorf("fetch is called %d times, expected 2", ring.fetches)
+	}
+}
+
+func TestFetchDoesNotRetryRejectedRequest(t *testing.T) {
+	ring := &rangeRing{fetchErrors: []error{
+		status.Error(codes.PermissionDenied, "denied"),
+		status.Error(codes.PermissionDenied, "denied"),
+	}}
+	remoteNode, sender := startReceiver(t, ring)
+	if _, err := sender.Fetch(remoteNode, "test", helpers.Hash("key")); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	if ring.fetches != 1 {
+		t.Errorf("rejected fetch is called %d times", ring.fetches)
+	}
+}
@@ -0,0 +1,53 @@
+package net
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/mbrostami/chord"
+	log "github.com/sirupsen/logrus"
+)
+
+// ResolveSeeds converts comma separated seed addresses to nodes, keeping their order
+// a host name is resolved to all of its addresses, so a single dns name can point to many seeds
+// seeds which can't be resolved are skipped, returns error if no seed is left
+func ResolveSeeds(addresses string) ([]*chord.Node, error) {
+	seeds := []*chord.Node{}
+	seen := make(map[string]bool)
+	for _, address := range strings.Split(addresses, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		host, portString, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("seed %s: %v", address, err)
+		}
+		port, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("seed %s: invalid port", address)
+		}
+		ips := []string{host}
+		if net.ParseIP(host) == nil {
+			ips, err = net.LookupHost(host)
+			if err != nil {
+				log.Warnf("seeds: resolving %s failed: %v", host, err)
+				continue
+			}
+		}
+		for _, ip := range ips {
+			seed := chord.NewNode(ip, uint(port))
+			if seen[seed.GetFullAddress()] {
+				continue
+			}
+			seen[seed.GetFullAddress()] = true
+			seeds = append(seeds, seed)
+		}
+	}
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("no seed is resolved from %q", addresses)
+	}
+	return seeds, nil
+}
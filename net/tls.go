@@ -0,0 +1,188 @@
+package net
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/credentials"
+)
+
+// certReloadInterval is the interval of checking certificate files for changes
+const certReloadInterval time.Duration = 10 * time.Second
+
+// TLSConfig certificates to secure node to node and client traffic
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string // CA bundle to verify peers, system pool is used if empty
+	// MutualTLS requires peer certificates on both sides
+	// peer certificate must match the node address it claims
+	MutualTLS bool
+}
+
+// certReloader keeps the certificate and CA pool loaded from files
+// and reloads them whenever files are changed
+type certReloader struct {
+	mutex       sync.RWMutex
+	config      *TLSConfig
+	certificate *tls.Certificate
+	caPool      *x509.CertPool
+	modTime     time.Time
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// newCertReloader loads certificates, certificate is optional for clients without mutual tls
+func newCertReloader(config *TLSConfig) (*certReloader, error) {
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, errors.New("tls: both certificate and key are required")
+	}
+	if config.MutualTLS && config.CertFile == "" {
+		return nil, errors.New("tls: certificate is required for mutual tls")
+	}
+	reloader := &certReloader{config: config, stop: make(chan struct{})}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	go reloader.watch()
+	return reloader, nil
+}
+
+// watch reloads certificates if files are changed
+// Runs periodically until the reloader is closed
+func (c *certReloader) watch() {
+	ticker := time.NewTicker(certReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		if !c.changed() {
+			continue
+		}
+		if err := c.reload(); err != nil {
+			log.Errorf("tls: reloading certificates failed, keep using old ones: %v", err)
+			continue
+		}
+		log.Info("tls: certificates are reloaded")
+	}
+}
+
+// Close stops watching the files, loaded certificates are kept
+func (c *certReloader) Close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// changed check if any of the files are modified since last reload
+func (c *certReloader) changed() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return latestModTime(c.config.CertFile, c.config.KeyFile, c.config.CAFile).After(c.modTime)
+}
+
+func (c *certReloader) reload() error {
+	modTime := latestModTime(c.config.CertFile, c.config.KeyFile, c.config.CAFile)
+	var certificate *tls.Certificate
+	if c.config.CertFile != "" {
+		keyPair, err := tls.LoadX509KeyPair(c.config.CertFile, c.config.KeyFile)
+		if err != nil {
+			return err
+		}
+		certificate = &keyPair
+	}
+	var caPool *x509.CertPool
+	if c.config.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.config.CAFile)
+		if err != nil {
+			return err
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(pem) {
+			return errors.New("tls: no certificate found in CA bundle " + c.config.CAFile)
+		}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.certificate = certificate
+	c.caPool = caPool
+	c.modTime = modTime
+	return nil
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.certificate, nil
+}
+
+func (c *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.certificate, nil
+}
+
+func (c *certReloader) getCAPool() *x509.CertPool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.caPool
+}
+
+// serverCredentials makes grpc server credentials using latest certificates for each handshake
+func (c *certReloader) serverCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(c.serverConfig())
+}
+
+// serverConfig makes server tls config using latest certificates for each handshake
+func (c *certReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := &tls.Config{
+				GetCertificate: c.getCertificate,
+				MinVersion:     tls.VersionTLS12,
+			}
+			if c.config.MutualTLS {
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+				config.ClientCAs = c.getCAPool()
+			}
+			return config, nil
+		},
+	}
+}
+
+// clientCredentials makes grpc client credentials using latest certificates
+// should be called for each new connection
+func (c *certReloader) clientCredentials() credentials.TransportCredentials {
+	config := &tls.Config{
+		RootCAs:    c.getCAPool(),
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.config.MutualTLS {
+		config.GetClientCertificate = c.getClientCertificate
+	}
+	return credentials.NewTLS(config)
+}
+
+func latestModTime(files ...string) time.Time {
+	var latest time.Time
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
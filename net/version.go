@@ -0,0 +1,109 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	chordGrpc "github.com/mbrostami/chord/grpc"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ProtocolVersion is the version of node to node protocol
+// it must be increased whenever semantics of a message changes
+// 1: nodes without handshake
+// 2: GlobalMaintenance returns only the digest, records are transferred by TransferRange
+// 3: root hash covers creation times, newer records replace replicas and deleted records are kept as tombstones
+const ProtocolVersion uint32 = 3
+
+// MinProtocolVersion is the oldest version of peers this node can talk to
+const MinProtocolVersion uint32 = 1
+
+// legacyProtocolVersion is assumed for peers which don't implement handshake
+const legacyProtocolVersion uint32 = 1
+
+// syncProtocolVersion is the oldest version data is synced with
+// older peers compare digests without creation times and drop tombstones, so they bring back older and deleted records
+const syncProtocolVersion uint32 = 3
+
+// features which can be missing in other nodes
+const (
+	FeatureTransferRange string = "transfer-range" // streaming range transfer
+	FeatureNeighbors     string = "neighbors"      // GetSuccessor, GetPredecessor and GetSuccessorList
+	FeatureMerge         string = "merge"          // ring unification after partitions
+	FeatureStatus        string = "status"         // ring introspection
+	FeatureScan          string = "scan"           // paged scan of a namespace for clients
+)
+
+// localFeatures are the features supported by this node
+var localFeatures = []string{FeatureTransferRange, FeatureNeighbors, FeatureMerge, FeatureStatus, FeatureScan}
+
+// handshakeTimeout is the deadline of handshake
+const handshakeTimeout time.Duration = 2 * time.Second
+
+// handshakeCacheTTL is the expiration of negotiated peer versions
+const handshakeCacheTTL time.Duration = 5 * time.Minute
+
+// ErrIncompatibleVersion is returned when the peer's protocol version is not supported
+var ErrIncompatibleVersion = errors.New("protocol version of the peer is not compatible")
+
+// ErrUnsupported is returned when the peer doesn't support the operation
+var ErrUnsupported = errors.New("operation is not supported by the peer")
+
+// PeerVersion is the negotiated protocol of a peer
+type PeerVersion struct {
+	Version    uint32
+	MinVersion uint32
+	Features   map[string]bool
+}
+
+// Supports check if the peer supports the feature
+func (p *PeerVersion) Supports(feature string) bool {
+	return p.Features[feature]
+}
+
+// localHello returns protocol information of this node
+func localHello() *chordGrpc.Hello {
+	return &chordGrpc.Hello{
+		Version:    ProtocolVersion,
+		MinVersion: MinProtocolVersion,
+		Features:   localFeatures,
+	}
+}
+
+// compatible check if two nodes can talk to each other
+func compatible(version uint32, minVersion uint32) bool {
+	return version >= MinProtocolVersion && minVersion <= ProtocolVersion
+}
+
+// handshake exchanges protocol version and features with the peer
+// peers without handshake rpc are considered as legacy nodes without any feature
+// the deadline of parent is kept if it's earlier than handshakeTimeout
+func handshake(parent context.Context, client chordGrpc.ChordClient, addr string) (*PeerVersion, error) {
+	ctx, cancel := context.WithTimeout(parent, handshakeTimeout)
+	defer cancel()
+	hello, err := client.Handshake(ctx, localHello())
+	if status.Code(err) == codes.Unimplemented {
+		hello = &chordGrpc.Hello{Version: legacyProtocolVersion, MinVersion: legacyProtocolVersion}
+	} else if err != nil {
+		return nil, err
+	}
+	peer := &PeerVersion{
+		Version:    hello.Version,
+		MinVersion: hello.MinVersion,
+		Features:   make(map[string]bool),
+	}
+	for _, feature := range hello.Features {
+		peer.Features[feature] = true
+	}
+	if peer.Version != ProtocolVersion {
+		log.Warnf("version: skew with %s, local version %d, peer version %d", addr, ProtocolVersion, peer.Version)
+	}
+	if !compatible(peer.Version, peer.MinVersion) {
+		log.Errorf("version: %s (version %d, min %d) is not compatible with local version %d", addr, peer.Version, peer.MinVersion, ProtocolVersion)
+		return peer, ErrIncompatibleVersion
+	}
+	return peer, nil
+}
@@ -13,14 +13,18 @@ type PredecessorList struct {
 
 // NewPredecessorList make new successor list
 func NewPredecessorList() *PredecessorList {
+	return newPredecessorList(RSIZE)
+}
+
+func newPredecessorList(r int) *PredecessorList {
 	return &PredecessorList{
 		Nodes: make(map[int]*RemoteNode),
-		r:     RSIZE,
+		r:     r,
 	}
 }
 
 // UpdatePredecessorList updates predecessor list
-func (pl *PredecessorList) UpdatePredecessorList(successor *RemoteNode, predecessor *RemoteNode, localNode *Node, predecessorList *PredecessorList) {
+func (pl *PredecessorList) UpdatePredecessorList(predecessor *RemoteNode, localNode *Node, predecessorList *PredecessorList) {
 	if predecessorList == nil || predecessor == nil {
 		return
 	}
@@ -34,21 +38,29 @@ func (pl *PredecessorList) UpdatePredecessorList(successor *RemoteNode, predeces
 			break
 		}
 		chorNode := predecessorList.Nodes[i]
-		// ignore same nodes
-		if chorNode.Identifier == localNode.Identifier {
-			continue
+		if chorNode == nil {
+			break
 		}
 		// in small networks where the number of nodes are smaller than pl.r number
-		// it's possible to have current node successor in predecessor's predecessorlist
-		// to prevent this loop, we should check predecessorlist and be sure to remove those
-		// in order to prevent a loop (including failed nodes)
-		if successor != nil {
-			if chorNode.Identifier == successor.Identifier {
-				break // skip next records including current one
-			}
+		// predecessor's predecessorlist wraps around the ring to the current node
+		// next records are repeated, so the list ends here
+		// successor is kept, it's the next predecessor if the ones before it fail
+		if chorNode.Identifier == localNode.Identifier {
+			break
 		}
 		pl.Nodes[index] = chorNode
 		index++
 	}
 
 }
+
+// GetNodes returns a sorted copy of predecessors
+func (pl *PredecessorList) GetNodes() []*RemoteNode {
+	pl.mutex.RLock()
+	defer pl.mutex.RUnlock()
+	nodes := []*RemoteNode{}
+	for i := 0; pl.Nodes[i] != nil; i++ {
+		nodes = append(nodes, pl.Nodes[i])
+	}
+	return nodes
+}
@@ -0,0 +1,95 @@
+package chord
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+// ErrInvalidSignature is returned when the record is not signed by a trusted writer key
+var ErrInvalidSignature = errors.New("record signature is not valid")
+
+// WriterKeys is the set of ed25519 public keys trusted to sign records
+type WriterKeys map[string]bool
+
+// NewWriterKeys parses base64 public keys separated by comma or new line
+func NewWriterKeys(definition string) (WriterKeys, error) {
+	keys := make(WriterKeys)
+	entries := strings.FieldsFunc(definition, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(entry)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("writer key must be a base64 ed25519 public key")
+		}
+		keys[string(key)] = true
+	}
+	return keys, nil
+}
+
+// LoadWriterKeys loads trusted writer keys from file, a base64 public key per line
+func LoadWriterKeys(file string) (WriterKeys, error) {
+	definition, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterKeys(string(definition))
+}
+
+// Trusts check if the key is one of the trusted writer keys
+func (w WriterKeys) Trusts(key []byte) bool {
+	return w[string(key)]
+}
+
+// Sign signs the record by the writer key
+func (r *Record) Sign(privateKey ed25519.PrivateKey) {
+	r.WriterKey = privateKey.Public().(ed25519.PublicKey)
+	r.Signature = ed25519.Sign(privateKey, r.signedContent())
+}
+
+// IsSigned check if record has a signature
+func (r *Record) IsSigned() bool {
+	return len(r.Signature) > 0
+}
+
+// VerifySignature check if the record is signed by its writer key and the writer key is trusted
+// anyone can sign a record by its own key, so a valid signature alone doesn't prove the writer
+func (r *Record) VerifySignature(trusted WriterKeys) error {
+	if !r.IsSigned() || len(r.WriterKey) != ed25519.PublicKeySize {
+		return ErrInvalidSignature
+	}
+	if !trusted.Trusts(r.WriterKey) {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(r.WriterKey, r.signedContent(), r.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signedContent is the signed part of the record: Identifier, Namespace, Content, CreationTime and Deleted
+// deleted flag is added only to tombstones, so signatures of older records stay valid
+func (r *Record) signedContent() []byte {
+	var content bytes.Buffer
+	content.Write(r.Identifier[:])
+	binary.Write(&content, binary.BigEndian, uint32(len(r.Namespace)))
+	content.WriteString(r.Namespace)
+	content.Write(r.Content)
+	binary.Write(&content, binary.BigEndian, r.CreationTime.UnixNano())
+	if r.Deleted {
+		content.WriteByte(1)
+	}
+	return content.Bytes()
+}
@@ -33,6 +33,30 @@ func (n *RemoteNode) GetStablizerData(local *Node) (*RemoteNode, *SuccessorList,
 	return NewRemoteNode(node, n.sender), successorList, err
 }
 
+// GetSuccessor remote node's successor
+func (n *RemoteNode) GetSuccessor() (*RemoteNode, error) {
+	node, err := n.sender.GetSuccessor(n)
+	if err != nil {
+		return nil, err
+	}
+	return NewRemoteNode(node, n.sender), nil
+}
+
+// GetPredecessor remote node's predecessor, nil if it's unknown
+// unlike GetStablizerData it doesn't update remote node's predecessor
+func (n *RemoteNode) GetPredecessor() (*RemoteNode, error) {
+	node, err := n.sender.GetPredecessor(n)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	return NewRemoteNode(node, n.sender), nil
+}
+
+// GetSuccessorList remote node's successor list
+func (n *RemoteNode) GetSuccessorList() (*SuccessorList, error) {
+	return n.sender.GetSuccessorList(n)
+}
+
 // GetPredecessorList predecessor's (predecessor list)
 func (n *RemoteNode) GetPredecessorList(local *Node) (*PredecessorList, error) {
 	predecessorList, err := n.sender.GetPredecessorList(n, local)
@@ -40,13 +64,18 @@ func (n *RemoteNode) GetPredecessorList(local *Node) (*PredecessorList, error) {
 }
 
 // Store store data on remote node
-func (n *RemoteNode) Store(data []byte) bool {
+func (n *RemoteNode) Store(data []byte) (bool, error) {
 	return n.sender.Store(n, data)
 }
 
+// StoreReplica push record to remote node as a replica
+func (n *RemoteNode) StoreReplica(data []byte) (bool, error) {
+	return n.sender.StoreReplica(n, data)
+}
+
 // Fetch get data from remote node
-func (n *RemoteNode) Fetch(key [helpers.HashSize]byte) []byte {
-	return n.sender.Fetch(n, key)
+func (n *RemoteNode) Fetch(namespace string, key [helpers.HashSize]byte) ([]byte, error) {
+	return n.sender.Fetch(n, namespace, key)
 }
 
 // Notify update predecessor
@@ -56,13 +85,24 @@ func (n *RemoteNode) Notify(local *Node) error {
 	return n.sender.Notify(n, local)
 }
 
-// Ping check if remote port is open - using to check predecessor state
-// FIXME should be cached
+// Ping check if remote node is serving - using to check predecessor state
+// results are cached by the ring
 // ref E.1
 func (n *RemoteNode) Ping() bool {
 	return n.sender.Ping(n)
 }
 
+// TransferRange receives records ∈ (from, to] of remote node in batches
+func (n *RemoteNode) TransferRange(local *Node, from [helpers.HashSize]byte, to [helpers.HashSize]byte, receive func(records []*Record) error) error {
+	return n.sender.TransferRange(n, local, from, to, receive)
+}
+
+// Merge asks remote node to place candidate in its ring
+// ref ring unification
+func (n *RemoteNode) Merge(local *Node, candidate *Node, hops int) error {
+	return n.sender.Merge(n, local, candidate, hops)
+}
+
 func (n *RemoteNode) GlobalMaintenance(data []byte) ([]byte, error) {
 	return n.sender.GlobalMaintenance(n, data)
 }
@@ -17,24 +17,48 @@ type RemoteNodeSenderInterface interface {
 	// ref E.3
 	GetStablizerData(remote *RemoteNode, local *Node) (*Node, *SuccessorList, error)
 
+	// GetSuccessor returns successor of remote node
+	GetSuccessor(remote *RemoteNode) (*Node, error)
+
+	// GetPredecessor returns predecessor of remote node without side effects
+	// returns nil if remote node has no predecessor
+	GetPredecessor(remote *RemoteNode) (*Node, error)
+
+	// GetSuccessorList returns successor list of remote node
+	// ref E.3
+	GetSuccessorList(remote *RemoteNode) (*SuccessorList, error)
+
 	// Notify update predecessor
 	// is being called periodically by predecessor or new node
 	// ref E.1
 	Notify(remote *RemoteNode, local *Node) error
 
-	// Ping check if remote port is open - using to check predecessor state
-	// FIXME should be cached
+	// Ping check if remote node is serving - using to check predecessor state
 	// ref E.1
 	Ping(remote *RemoteNode) bool
 
 	GlobalMaintenance(remote *RemoteNode, data []byte) ([]byte, error)
 
 	// Store store data in remote node
-	Store(remote *RemoteNode, data []byte) bool
+	// returns ErrNotOwner if data is out of remote node's range
+	Store(remote *RemoteNode, data []byte) (bool, error)
 
-	// Store store data in remote node
-	Fetch(remote *RemoteNode, key [helpers.HashSize]byte) []byte
+	// StoreReplica pushes the record of the local node to remote node as a replica
+	// returns ErrNotOwner if data is out of remote node's range
+	StoreReplica(remote *RemoteNode, data []byte) (bool, error)
+
+	// Fetch get data from remote node
+	// returns ErrNotOwner if key is out of remote node's range
+	Fetch(remote *RemoteNode, namespace string, key [helpers.HashSize]byte) ([]byte, error)
 
 	// GetPredecessorList
 	GetPredecessorList(remote *RemoteNode, local *Node) (*PredecessorList, error)
+
+	// Merge asks remote node to place candidate in its ring, candidate may be from another partition
+	// remote node adopts candidate as successor or forwards it, at most hops times
+	Merge(remote *RemoteNode, local *Node, candidate *Node, hops int) error
+
+	// TransferRange receives records ∈ (from, to] of remote node in batches
+	// next batch is not requested before receive returns
+	TransferRange(remote *RemoteNode, local *Node, from [helpers.HashSize]byte, to [helpers.HashSize]byte, receive func(records []*Record) error) error
 }
@@ -14,14 +14,29 @@ func (m MockRemoteNodeSenderInterface) FindSuccessor(remote *RemoteNode, identif
 func (m MockRemoteNodeSenderInterface) GetStablizerData(remote *RemoteNode, local *Node) (*Node, *SuccessorList, error) {
 	return nil, nil, nil
 }
+func (m MockRemoteNodeSenderInterface) GetSuccessor(remote *RemoteNode) (*Node, error) {
+	return nil, nil
+}
+func (m MockRemoteNodeSenderInterface) GetPredecessor(remote *RemoteNode) (*Node, error) {
+	return nil, nil
+}
+func (m MockRemoteNodeSenderInterface) GetSuccessorList(remote *RemoteNode) (*SuccessorList, error) {
+	return nil, nil
+}
 func (m MockRemoteNodeSenderInterface) Notify(remote *RemoteNode, local *Node) error {
 	return nil
 }
 func (m MockRemoteNodeSenderInterface) Ping(remote *RemoteNode) bool {
 	return true
 }
-func (m MockRemoteNodeSenderInterface) Store(remote *RemoteNode, data []byte) bool {
-	return true
+func (m MockRemoteNodeSenderInterface) Store(remote *RemoteNode, data []byte) (bool, error) {
+	return true, nil
+}
+func (m MockRemoteNodeSenderInterface) StoreReplica(remote *RemoteNode, data []byte) (bool, error) {
+	return true, nil
+}
+func (m MockRemoteNodeSenderInterface) Fetch(remote *RemoteNode, namespace string, key [helpers.HashSize]byte) ([]byte, error) {
+	return nil, nil
 }
 func (m MockRemoteNodeSenderInterface) GetPredecessorList(remote *RemoteNode, local *Node) (*PredecessorList, error) {
 	return nil, nil
@@ -29,3 +44,9 @@ func (m MockRemoteNodeSenderInterface) GetPredecessorList(remote *RemoteNode, lo
 func (m MockRemoteNodeSenderInterface) GlobalMaintenance(remote *RemoteNode, data []byte) ([]byte, error) {
 	return nil, nil
 }
+func (m MockRemoteNodeSenderInterface) TransferRange(remote *RemoteNode, local *Node, from [helpers.HashSize]byte, to [helpers.HashSize]byte, receive func(records []*Record) error) error {
+	return nil
+}
+func (m MockRemoteNodeSenderInterface) Merge(remote *RemoteNode, local *Node, candidate *Node, hops int) error {
+	return nil
+}
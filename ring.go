@@ -2,15 +2,68 @@ package chord
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/mbrostami/chord/helpers"
+	"github.com/patrickmn/go-cache"
 	log "github.com/sirupsen/logrus"
 )
 
-const replicas int = 3
+// REPLICAS is the default number of nodes keeping each record
+const REPLICAS int = 3
+
+// proximityCandidates is the number of nodes to compare in proximity neighbor selection
+// ref Proximity Neighbor Selection (PNS)
+const proximityCandidates int = 4
+
+// pingCacheTTL is the expiration of cached ping results
+const pingCacheTTL time.Duration = 2 * time.Second
+
+// reencryptBatch is the maximum number of records re-encrypted in each maintenance run
+const reencryptBatch int = 100
+
+// transferBatch is the maximum number of records in each batch of range transfer
+const transferBatch int = 100
+
+// transferBatchBytes is the maximum size of record contents in each batch of range transfer
+// to stay far below grpc message size limit
+const transferBatchBytes int = 1 << 20
+
+// probeBatch is the number of remembered members probed in each round
+const probeBatch int = 3
+
+// mergeHops is the maximum number of nodes a merge request passes through
+const mergeHops int = 32
+
+// mergeQueueSize is the maximum number of merge requests waiting for the maintenance, others are dropped
+const mergeQueueSize int = 16
+
+// lookupAttempts is the maximum number of candidates tried in each hop of a lookup
+const lookupAttempts int = 3
+
+// fingerLookups is the maximum number of lookups in each call of FixFingers
+// an unfinished round is continued by the next call
+const fingerLookups int = 32
+
+// ErrJoinFailed is returned when none of the seeds could find the node's successor
+var ErrJoinFailed = errors.New("join failed, no seed is reachable")
+
+// ErrNotOwner is returned when the requested key is out of the node's range
+var ErrNotOwner = errors.New("key is not in the node's range")
+
+// ErrInvalidRecord is returned when the stored data is not a json record
+var ErrInvalidRecord = errors.New("record is not valid json")
+
+// ErrLookupFailed is returned by a hop which is alive but couldn't find the successor in the next hops
+var ErrLookupFailed = errors.New("lookup failed in the next hops")
 
 type Ring struct {
+	config          Config
 	localNode       *Node
 	remoteSender    RemoteNodeSenderInterface
 	fingerTable     *FingerTable
@@ -20,47 +73,188 @@ type Ring struct {
 	predecessor     *RemoteNode
 	successor       *RemoteNode
 	dstore          *DStore
+	latencyTable    *LatencyTable
+	locationCache   *LocationCache
+	pingCache       *cache.Cache
+	failureDetector *FailureDetector
+	members         *Members
+	ready           bool
+	// requireSignedRecords rejects records without valid writer signature
+	requireSignedRecords bool
+	// trustedWriters are the public keys allowed to sign records
+	trustedWriters WriterKeys
+	// keyRing encrypts record contents at rest, nil means plain
+	keyRing *KeyRing
+	// reencryptCursor is the last record visited by Reencrypt, nil starts from the first record
+	reencryptCursor *RangeCursor
+	// merges are queued merge requests, they are applied by Stabilize
+	merges chan mergeRequest
+	clock  Clock
+}
+
+// mergeRequest is a candidate waiting to be placed in the ring
+type mergeRequest struct {
+	candidate *Node
+	hops      int
 }
 
-func NewRing(localNode *Node, remoteSender RemoteNodeSenderInterface) RingInterface {
-	var ring RingInterface
-	successorList := NewSuccessorList()
-	predecessorList := NewPredecessorList()
-	ring = &Ring{
-		localNode:       localNode,
-		remoteSender:    remoteSender,
-		fingerTable:     NewFingerTable(),
-		stabilizer:      NewStabilizer(successorList, predecessorList),
-		successorList:   successorList,
-		predecessorList: predecessorList,
-		successor:       NewRemoteNode(localNode, remoteSender),
-		predecessor:     nil,
-		dstore:          NewDStore(localNode.GetFullAddress()),
+// pingResult is the cached result of a health check
+type pingResult struct {
+	alive bool
+	at    time.Time
+}
+
+// NewRing make new ring of the local node, zero values of config are replaced by defaults
+// returns error if config is not valid
+func NewRing(localNode *Node, remoteSender RemoteNodeSenderInterface, config Config) (RingInterface, error) {
+	config = config.withDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
-	return ring
+	successorList := newSuccessorList(config.SuccessorListSize)
+	predecessorList := newPredecessorList(config.SuccessorListSize)
+	failureDetector := NewFailureDetector(config.PhiThreshold)
+	failureDetector.SetClock(config.Clock)
+	locationCache := NewLocationCache(config.LocationCacheTTL)
+	locationCache.SetClock(config.Clock)
+	members := NewMembers(config.MemberTTL)
+	members.SetClock(config.Clock)
+	ring := &Ring{
+		config:               config,
+		localNode:            localNode,
+		remoteSender:         remoteSender,
+		fingerTable:          NewFingerTable(),
+		stabilizer:           NewStabilizer(successorList, predecessorList, failureDetector),
+		successorList:        successorList,
+		predecessorList:      predecessorList,
+		successor:            NewRemoteNode(localNode, remoteSender),
+		predecessor:          nil,
+		dstore:               NewDStore(DStorePath(config.DataDir, localNode.GetFullAddress())),
+		latencyTable:         NewLatencyTable(),
+		locationCache:        locationCache,
+		pingCache:            cache.New(cache.NoExpiration, 0), // expired by ring's clock
+		failureDetector:      failureDetector,
+		members:              members,
+		merges:               make(chan mergeRequest, mergeQueueSize),
+		clock:                config.Clock,
+		keyRing:              config.KeyRing,
+		requireSignedRecords: config.RequireSignedRecords,
+		trustedWriters:       config.TrustedWriters,
+	}
+	return ring, nil
 }
 
-// Join throw first node
+// Create creates a new ring with the local node as the only member
 // ref E.1
-func (r *Ring) Join(remoteNode *RemoteNode) error {
-	successor, err := remoteNode.FindSuccessor(r.localNode.Identifier)
-	if err != nil {
-		log.Errorf("Error Join: %v", err)
-		return err
+func (r *Ring) Create() {
+	r.predecessor = nil
+	r.successor = NewRemoteNode(r.localNode, r.remoteSender)
+	r.ready = true
+}
+
+// IsReady returns true if the node is created or joined to a ring
+func (r *Ring) IsReady() bool {
+	return r.ready
+}
+
+// Join joins the ring through the first seed which finds the node's successor
+// seeds are tried in order, the local node is skipped if it's listed as a seed
+// ref E.1
+func (r *Ring) Join(seeds ...*RemoteNode) error {
+	failures := []string{}
+	for _, seed := range seeds {
+		if seed.GetFullAddress() == r.localNode.GetFullAddress() {
+			continue
+		}
+		successor, err := seed.FindSuccessor(r.localNode.Identifier)
+		if err != nil {
+			log.Warnf("ring:Join seed %s failed: %v", seed.GetFullAddress(), err)
+			failures = append(failures, fmt.Sprintf("%s: %v", seed.GetFullAddress(), err))
+			continue
+		}
+		r.join(successor)
+		return nil
+	}
+	if len(failures) == 0 {
+		return fmt.Errorf("%w: no seed other than the local node", ErrJoinFailed)
 	}
+	return fmt.Errorf("%w: %s", ErrJoinFailed, strings.Join(failures, "; "))
+}
+
+// join sets the successor found by a seed and takes over its range
+func (r *Ring) join(successor *RemoteNode) {
 	//fmt.Printf("Join: got successor %s:%d! \n", successor.IP, successor.Port)
 	r.predecessor = nil
-	r.successor = successor
+	r.setSuccessor(successor)
+	r.members.Add(successor.Node)
+	r.locationCache.Clear()
 	r.fingerTable.Set(1, r.successor)
+	// successor keeps serving the range until it's notified
+	r.handoff(successor)
 	r.successor.Notify(r.localNode)
-	return nil
+	r.ready = true
+}
+
+// handoff pulls the records of the new node's range from the successor
+// (successor, n] contains the node's range and replicas of its predecessors' ranges
+// failures are repaired later by SyncData
+func (r *Ring) handoff(successor *RemoteNode) {
+	if successor.Identifier == r.localNode.Identifier {
+		return
+	}
+	transferred := 0
+	err := successor.TransferRange(r.localNode, successor.Identifier, r.localNode.Identifier, func(records []*Record) error {
+		for _, record := range records {
+			if r.isResponsible(record.Identifier) {
+				r.storeReplica(record)
+			} else {
+				// e.g. records of a merged ring which are far from their new owners
+				r.forwardRecord(record)
+			}
+		}
+		transferred += len(records)
+		return nil
+	})
+	if err != nil {
+		log.Warnf("ring:handoff transfer from %s failed after %d records: %v", successor.GetFullAddress(), transferred, err)
+		return
+	}
+	log.Infof("ring:handoff received %d records from %s", transferred, successor.GetFullAddress())
+}
+
+// forwardRecord stores the record on its owner
+func (r *Ring) forwardRecord(record *Record) {
+	owner := r.findSuccessor(record.Identifier, false)
+	if owner == nil || owner.Identifier == r.localNode.Identifier {
+		r.storeReplica(record)
+		return
+	}
+	if _, err := owner.StoreReplica(record.GetJson()); err != nil {
+		log.Warnf("ring:forwardRecord storing %x on %s failed: %v", record.Identifier, owner.GetFullAddress(), err)
+	}
 }
 
 func (r *Ring) GetLocalNode() *Node {
 	return r.localNode
 }
 
+// GetFailureDetector returns failure detector of the remote nodes
+func (r *Ring) GetFailureDetector() *FailureDetector {
+	return r.failureDetector
+}
+
 func (r *Ring) FindSuccessor(identifier [helpers.HashSize]byte) *RemoteNode {
+	return r.findSuccessor(identifier, true)
+}
+
+// FindSuccessorUncached find the closest node without using cached owners
+func (r *Ring) FindSuccessorUncached(identifier [helpers.HashSize]byte) *RemoteNode {
+	return r.findSuccessor(identifier, false)
+}
+
+// findSuccessor find the closest node to the given identifier
+// cached owners are used to skip the lookup if useCache is set
+func (r *Ring) findSuccessor(identifier [helpers.HashSize]byte, useCache bool) *RemoteNode {
 	// fmt.Printf("FindSuccessor: start looking for key %x \n", identifier)
 	if r.successor.Identifier == r.localNode.Identifier {
 		return NewRemoteNode(r.localNode, r.remoteSender)
@@ -69,48 +263,126 @@ func (r *Ring) FindSuccessor(identifier [helpers.HashSize]byte) *RemoteNode {
 	if helpers.BetweenR(identifier, r.localNode.Identifier, r.successor.Identifier) {
 		return r.successor
 	}
-	closestRemoteNode := r.fingerTable.ClosestPrecedingNode(identifier, r.localNode)
-	successorListClosestNode := r.successorList.ClosestPrecedingNode(identifier, r.localNode, closestRemoteNode)
-	if successorListClosestNode != nil {
-		closestRemoteNode = successorListClosestNode
-	} else if closestRemoteNode == nil {
-		closestRemoteNode = NewRemoteNode(r.localNode, r.remoteSender) // make a copy local node as remote node
+	if useCache {
+		if owner := r.locationCache.Lookup(identifier); owner != nil {
+			return owner
+		}
 	}
-	if closestRemoteNode.Identifier == r.localNode.Identifier { // current node is the only node in figer table
-		return closestRemoteNode // return local node
+	candidates := r.closestPrecedingNodes(identifier)
+	if len(candidates) == 0 { // current node is the only node in figer table
+		return NewRemoteNode(r.localNode, r.remoteSender) // make a copy local node as remote node
 	}
-	nextNodeSuccessor, err := closestRemoteNode.FindSuccessor(identifier)
-	if err != nil { // unexpected error on successor
-		log.Errorf("Unexpected error from successor %v", err)
-		return nil
+	// if the closest node is failed, fallback to the next best finger or successor
+	// a candidate which answers that the lookup failed downstream is alive, so the failure is returned
+	// otherwise each hop would try all of its candidates again
+	if len(candidates) > lookupAttempts {
+		candidates = candidates[:lookupAttempts]
 	}
-	return nextNodeSuccessor
+	for _, candidate := range candidates {
+		nextNodeSuccessor, err := candidate.FindSuccessor(identifier)
+		if err == ErrLookupFailed {
+			r.failureDetector.Heartbeat(candidate.Identifier)
+			r.fingerTable.Unsuspect(candidate)
+			log.Warnf("ring:FindSuccessor lookup of %x failed after hop %s", identifier, candidate.GetFullAddress())
+			return nil
+		}
+		if err != nil { // candidate is not reachable
+			log.Warnf("ring:FindSuccessor hop %s failed, trying next candidate: %v", candidate.GetFullAddress(), err)
+			r.fingerTable.Suspect(candidate)
+			r.locationCache.InvalidateOwner(candidate.Identifier)
+			continue
+		}
+		r.failureDetector.Heartbeat(candidate.Identifier)
+		r.fingerTable.Unsuspect(candidate)
+		r.members.Add(nextNodeSuccessor.Node)
+		// a node between the local node and its successor means rings are inconsistent,
+		// e.g. fingers still point to the other side of a healed partition
+		if helpers.Between(nextNodeSuccessor.Identifier, r.localNode.Identifier, r.successor.Identifier) {
+			r.Merge(nextNodeSuccessor.Node, mergeHops)
+		}
+		r.locationCache.Add(identifier, nextNodeSuccessor)
+		return nextNodeSuccessor
+	}
+	log.Errorf("ring:FindSuccessor all %d candidates failed for %x", len(candidates), identifier)
+	return nil
+}
+
+// closestPrecedingNodes merges finger table and successor list nodes ∈ (n, id)
+// sorted from the closest to the farthest preceding node of the identifier
+// nodes which failed in previous lookups are tried last, they might be alive again (e.g. after a partition)
+// and their suspicion is cleared when they answer a lookup or FixFingers sets them
+// failure detector is not used, since fingers are not heartbeated and they would be suspected soon after each contact
+// ref D - E.3
+func (r *Ring) closestPrecedingNodes(identifier [helpers.HashSize]byte) []*RemoteNode {
+	seen := make(map[[helpers.HashSize]byte]bool)
+	candidates := []*RemoteNode{}
+	suspected := []*RemoteNode{}
+	nodes := append(
+		r.fingerTable.ClosestPrecedingNodes(identifier, r.localNode),
+		r.successorList.ClosestPrecedingNodes(identifier, r.localNode)...,
+	)
+	for _, node := range nodes {
+		if seen[node.Identifier] || node.Identifier == r.localNode.Identifier {
+			continue
+		}
+		seen[node.Identifier] = true
+		if r.fingerTable.IsSuspected(node) {
+			suspected = append(suspected, node)
+			continue
+		}
+		candidates = append(candidates, node)
+	}
+	r.sortByDistance(candidates)
+	r.sortByDistance(suspected)
+	return append(candidates, suspected...)
+}
+
+// sortByDistance sorts nodes from the closest to the farthest preceding node of the identifier
+func (r *Ring) sortByDistance(nodes []*RemoteNode) {
+	// a is closer to the identifier than b, if b ∈ (n, a)
+	sort.SliceStable(nodes, func(a, b int) bool {
+		return helpers.Between(nodes[b].Identifier, r.localNode.Identifier, nodes[a].Identifier)
+	})
 }
 
 // Stabilize keep successor and predecessor updated
 // Runs periodically
 // ref E.1 - E.3
 func (r *Ring) Stabilize() {
+	r.applyMerges()
 	successor, successorList, err := r.stabilizer.StartSuccessorList(r.successor, r.localNode)
+	if err == errSuccessorNotSuspected {
+		return
+	}
 	if err != nil {
 		// all successors are failed
 		r.successor = NewRemoteNode(r.localNode, r.remoteSender)
 		return
 	}
+	known := r.successorList.Contains(successor.Identifier)
 	// Update successor list - ref E.3
-	r.successorList.UpdateSuccessorList(successor, r.predecessor, r.localNode, successorList)
+	r.successorList.UpdateSuccessorList(successor, r.localNode, successorList)
+	for _, node := range r.successorList.GetNodes() {
+		r.members.Add(node.Node)
+	}
 	// If successor is changed while stabilizing
 	if successor.Identifier != r.successor.Identifier {
-		r.successor = successor
+		r.setSuccessor(successor)
+		r.locationCache.Clear()
 		r.fingerTable.Set(1, r.successor)
 		// immediatly update new successor about it's new predecessor
 		r.successor.Notify(r.localNode)
+		if !known {
+			// successor is a new node, e.g. joined or came from a merged ring
+			// records of a merged ring can be far from their new owners, so they are pulled back
+			r.handoff(r.successor)
+		}
 	}
 
 	// update predecessor list
 	// TODO can be replaces ping predecessor
 	predecessor, predecessorList, err := r.stabilizer.StartPredecessorList(r.predecessor, r.localNode)
-	r.predecessorList.UpdatePredecessorList(r.successor, predecessor, r.localNode, predecessorList)
+	r.predecessorList.UpdatePredecessorList(predecessor, r.localNode, predecessorList)
 	if r.predecessor == nil {
 		return
 	}
@@ -120,10 +392,19 @@ func (r *Ring) Stabilize() {
 	}
 }
 
+// setSuccessor replaces the successor and tracks it in the failure detector
+// so it's suspected if it never responds
+func (r *Ring) setSuccessor(successor *RemoteNode) {
+	r.successor = successor
+	r.failureDetector.Track(successor.Identifier)
+}
+
 // Notify update predecessor
 // is being called periodically by predecessor or new node
 // ref E.1
 func (r *Ring) Notify(caller *Node) bool {
+	r.failureDetector.Heartbeat(caller.Identifier)
+	r.members.Add(caller)
 	// (c.predecessor is nil or node ∈ (c.predecessor, n))
 	if r.predecessor == nil {
 		r.predecessor = NewRemoteNode(caller, r.remoteSender)
@@ -131,41 +412,281 @@ func (r *Ring) Notify(caller *Node) bool {
 		log.Info("Notify applying!")
 		if r.successor.Identifier == r.localNode.Identifier {
 			log.Info("Bootstrap successor is changed!")
-			r.successor = r.predecessor
+			r.setSuccessor(r.predecessor)
 			r.successor.Notify(r.localNode)
 		}
 		return true
 	}
 	if helpers.Between(caller.Identifier, r.predecessor.Identifier, r.localNode.Identifier) {
 		r.predecessor = NewRemoteNode(caller, r.remoteSender)
+		r.locationCache.Clear()
 		return true
 	}
 	return false
 }
 
+// CheckPredecessor removes predecessor if it's suspected as failed
+// a single failed ping is not enough, the suspicion level must be above the threshold
 func (r *Ring) CheckPredecessor() {
 	if r.predecessor != nil {
-		if !r.predecessor.Ping() {
+		if !r.ping(r.predecessor) && r.failureDetector.Suspected(r.predecessor.Identifier) {
 			r.predecessor = nil // set nil to be able to update predecessor by notify
 		}
 	}
 }
 
-// FixFingers refreshes finger table entities
+// ProbeMembers pings some of the remembered members and merges the alive ones which are missing in the ring
+// a member is missing if lookup of its identifier finds another node, e.g. after a partition is healed
+// Runs periodically
+func (r *Ring) ProbeMembers() {
+	if !r.ready {
+		return
+	}
+	for _, member := range r.members.Next(probeBatch) {
+		if member.Identifier == r.localNode.Identifier {
+			continue
+		}
+		if !r.ping(NewRemoteNode(member, r.remoteSender)) {
+			continue // forgotten if it's not seen until ttl
+		}
+		r.members.Add(member)
+		owner := r.findSuccessor(member.Identifier, false)
+		if owner != nil && owner.Identifier == member.Identifier {
+			continue
+		}
+		log.Warnf("ring:ProbeMembers %s is alive but missing in the ring, merging", member.GetFullAddress())
+		r.Merge(member, mergeHops)
+	}
+	r.forgetDeparted()
+}
+
+// forgetDeparted removes failure detector histories and latency estimates of the nodes which are not known anymore
+// a node is known while it's a neighbor, a finger or a member, so it's forgotten after it left all of them
+// e.g. it's dropped from successor list, replaced in fingers and its membership is expired
+// stale latencies of departed nodes would steer proximity neighbor selection if they joined again
+func (r *Ring) forgetDeparted() {
+	known := r.knownNodes()
+	for _, identifier := range r.failureDetector.Tracked() {
+		if !known[identifier] && !r.members.Contains(identifier) {
+			r.failureDetector.Forget(identifier)
+		}
+	}
+	for _, identifier := range r.latencyTable.Identifiers() {
+		if !known[identifier] && !r.members.Contains(identifier) {
+			r.latencyTable.Forget(identifier)
+		}
+	}
+}
+
+// knownNodes returns identifiers of successor, predecessor, successor list, predecessor list and fingers
+func (r *Ring) knownNodes() map[[helpers.HashSize]byte]bool {
+	known := make(map[[helpers.HashSize]byte]bool)
+	nodes := append(r.successorList.GetNodes(), r.predecessorList.GetNodes()...)
+	for _, finger := range r.fingerTable.Fingers() {
+		nodes = append(nodes, finger)
+	}
+	nodes = append(nodes, r.successor)
+	if r.predecessor != nil {
+		nodes = append(nodes, r.predecessor)
+	}
+	for _, node := range nodes {
+		known[node.Identifier] = true
+	}
+	return known
+}
+
+// Merge queues candidate to be placed in the local ring, candidate may be a member of another ring
+// it doesn't block, so lookups and remote merge requests are not held by forwards and handoffs
+// the request is dropped if the queue is full, members are probed again later
+func (r *Ring) Merge(candidate *Node, hops int) {
+	if candidate == nil || hops <= 0 {
+		return
+	}
+	select {
+	case r.merges <- mergeRequest{candidate: candidate, hops: hops}:
+	default:
+		log.Debugf("ring:Merge queue is full, %s is dropped", candidate.GetFullAddress())
+	}
+}
+
+// applyMerges applies queued merge requests, it's called by the maintenance
+func (r *Ring) applyMerges() {
+	for {
+		select {
+		case request := <-r.merges:
+			r.merge(request.candidate, request.hops)
+		default:
+			return
+		}
+	}
+}
+
+// merge places candidate in the local ring
+// candidate becomes successor if it's ∈ (n, successor), otherwise it's forwarded to the closest preceding node
+// the adopted successor is asked to place the local node in its ring too, so both rings are unified
+// ref ring unification
+func (r *Ring) merge(candidate *Node, hops int) {
+	if !r.ready {
+		return
+	}
+	if candidate.Identifier == r.localNode.Identifier || candidate.Identifier == r.successor.Identifier {
+		return
+	}
+	if r.successor.Identifier != r.localNode.Identifier &&
+		!helpers.Between(candidate.Identifier, r.localNode.Identifier, r.successor.Identifier) {
+		for _, next := range r.closestPrecedingNodes(candidate.Identifier) {
+			if err := next.Merge(r.localNode, candidate, hops-1); err == nil {
+				return
+			}
+		}
+		return
+	}
+	successor := NewRemoteNode(candidate, r.remoteSender)
+	if !r.verifyCandidate(successor) {
+		return
+	}
+	log.Warnf("ring:Merge %s is adopted as successor instead of %s", successor.GetFullAddress(), r.successor.GetFullAddress())
+	r.setSuccessor(successor)
+	r.members.Add(candidate)
+	r.locationCache.Clear()
+	r.fingerTable.Set(1, r.successor)
+	r.successor.Notify(r.localNode)
+	// records of the merged range are pulled like a join, replicas are repaired by SyncData
+	r.handoff(r.successor)
+	if err := r.successor.Merge(r.localNode, r.localNode, hops-1); err != nil {
+		log.Warnf("ring:Merge %s failed to place local node: %v", r.successor.GetFullAddress(), err)
+	}
+}
+
+// verifyCandidate check if the candidate owns the identifier it claims
+// the candidate is asked for the successor of its identifier, which must be the candidate itself
+// otherwise any node could make others adopt an address with a forged identifier
+func (r *Ring) verifyCandidate(candidate *RemoteNode) bool {
+	owner, err := candidate.FindSuccessor(candidate.Identifier)
+	if err != nil {
+		log.Warnf("ring:Merge candidate %s is not reachable: %v", candidate.GetFullAddress(), err)
+		return false
+	}
+	r.failureDetector.Heartbeat(candidate.Identifier)
+	if owner == nil || owner.Identifier != candidate.Identifier || owner.GetFullAddress() != candidate.GetFullAddress() {
+		log.Warnf("ring:Merge candidate %s does not own its identifier %x", candidate.GetFullAddress(), candidate.Identifier)
+		return false
+	}
+	return true
+}
+
+// FixFingers refreshes all distinct finger table entities in one round
+// only O(log n) lookups are needed, since consecutive fingers often share the same successor
+// lookups are bounded, so a degraded ring doesn't make a round run all the fingers
 // Runs periodically
 // ref D - E.1 - finger[k] = (n + 2 ** k-1) Mod M
 func (r *Ring) FixFingers() {
 	if r.successor == nil {
 		return
 	}
+	for lookups := 0; lookups < fingerLookups; lookups++ {
+		index := r.fixFinger()
+		if index == 1 && lookups > 0 { // round is completed
+			return
+		}
+	}
+}
+
+// fixFinger refreshes next distinct finger and returns its index
+func (r *Ring) fixFinger() int {
 	index, identifier := r.fingerTable.CalculateIdentifier(r.localNode)
-	remoteNode := r.FindSuccessor(identifier)
-	r.fingerTable.Set(index, remoteNode)
+	// fingers must reflect the latest topology, so cache is not used
+	remoteNode := r.findSuccessor(identifier, false)
+	if remoteNode == nil { // lookup failed, keep the old finger and try again next round
+		r.fingerTable.Skip(index)
+		return index
+	}
+	if index > 1 { // first entry should be always the next successor of current node
+		remoteNode = r.closestFinger(index, remoteNode)
+	}
+	r.fingerTable.Fix(index, remoteNode)
 	if index == 1 && remoteNode.Identifier != r.successor.Identifier { // means it's first entry of fingerTable (first entry should be always the next successor of current node)
-		r.successor = remoteNode
+		r.setSuccessor(remoteNode)
 		// immediatly update new successor about it's new predecessor
 		r.successor.Notify(r.localNode)
 	}
+	return index
+}
+
+// closestFinger picks the lowest latency node among the successors of the ideal finger
+// which are still in the finger interval
+// ref Proximity Neighbor Selection (PNS)
+func (r *Ring) closestFinger(index int, ideal *RemoteNode) *RemoteNode {
+	if ideal.Identifier == r.localNode.Identifier {
+		return ideal
+	}
+	start, end := r.fingerTable.Interval(r.localNode, index)
+	if !helpers.BetweenL(ideal.Identifier, start, end) { // even ideal node is out of interval
+		return ideal
+	}
+	candidates := []*RemoteNode{ideal}
+	successorList, err := ideal.GetSuccessorList()
+	if err == nil && successorList != nil {
+		for i := 0; i < len(successorList.Nodes) && len(candidates) < proximityCandidates; i++ {
+			candidate := successorList.Nodes[i]
+			// successor[i] ∈ [finger[k].start, finger[k+1].start)
+			if !helpers.BetweenL(candidate.Identifier, start, end) {
+				break // successors are sorted, the rest are out of interval
+			}
+			if candidate.Identifier == r.localNode.Identifier {
+				break
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+	closest := ideal
+	closestRTT := time.Duration(math.MaxInt64)
+	for _, candidate := range candidates {
+		rtt, found := r.latencyTable.Get(candidate.Identifier)
+		if !found {
+			if !r.ping(candidate) {
+				continue
+			}
+			rtt, _ = r.latencyTable.Get(candidate.Identifier)
+		}
+		if rtt < closestRTT {
+			closest = candidate
+			closestRTT = rtt
+		}
+	}
+	return closest
+}
+
+// ping checks remote node and keeps the round trip time
+// to be used in proximity neighbor selection
+// results are cached for a short time to prevent duplicate health checks
+func (r *Ring) ping(remoteNode *RemoteNode) bool {
+	key := string(remoteNode.Identifier[:])
+	if x, found := r.pingCache.Get(key); found {
+		if result := x.(*pingResult); r.clock.Now().Sub(result.at) < pingCacheTTL {
+			return result.alive
+		}
+	}
+	start := r.clock.Now()
+	alive := remoteNode.Ping()
+	if alive {
+		r.latencyTable.Observe(remoteNode.Identifier, r.clock.Now().Sub(start))
+		r.failureDetector.Heartbeat(remoteNode.Identifier)
+	} else {
+		r.failureDetector.Track(remoteNode.Identifier)
+	}
+	r.pingCache.Set(key, &pingResult{alive: alive, at: r.clock.Now()}, cache.NoExpiration)
+	return alive
+}
+
+// GetSuccessor returns successor
+func (r *Ring) GetSuccessor() *RemoteNode {
+	return r.successor
+}
+
+// GetCurrentPredecessor returns predecessor, unlike GetPredecessor caller can't replace it
+func (r *Ring) GetCurrentPredecessor() *RemoteNode {
+	return r.predecessor
 }
 
 // GetSuccessorList returns unsorted successor list
@@ -179,6 +700,28 @@ func (r *Ring) GetPredecessorList(caller *Node) *PredecessorList {
 	return r.predecessorList
 }
 
+// Status returns the view of the local node on the ring, without side effects
+func (r *Ring) Status() *RingStatus {
+	status := &RingStatus{
+		Node:      r.localNode,
+		Successor: r.successor.Node,
+		Fingers:   make(map[int]*Node),
+	}
+	if predecessor := r.GetCurrentPredecessor(); predecessor != nil {
+		status.Predecessor = predecessor.Node
+	}
+	for _, successor := range r.successorList.GetNodes() {
+		status.SuccessorList = append(status.SuccessorList, successor.Node)
+	}
+	for _, predecessor := range r.predecessorList.GetNodes() {
+		status.PredecessorList = append(status.PredecessorList, predecessor.Node)
+	}
+	for index, finger := range r.fingerTable.Fingers() {
+		status.Fingers[index] = finger.Node
+	}
+	return status
+}
+
 // GetStabilizerData return predecessor and successor list
 func (r *Ring) GetStabilizerData(caller *Node) (*RemoteNode, *SuccessorList) {
 	remoteCaller := NewRemoteNode(caller, r.remoteSender)
@@ -187,13 +730,12 @@ func (r *Ring) GetStabilizerData(caller *Node) (*RemoteNode, *SuccessorList) {
 }
 
 // SyncData sync local data with successor
-// FIXME too many open files
 func (r *Ring) SyncData() error {
 	// ignore self sync
 	if r.successor.Identifier == r.localNode.Identifier {
 		return nil
 	}
-	lastPredIndex := replicas - 2
+	lastPredIndex := r.config.Replicas - 2
 
 	// in order to sync data with successor, we should know about predecessors first
 	if r.predecessorList.Nodes[lastPredIndex] == nil {
@@ -207,7 +749,11 @@ func (r *Ring) SyncData() error {
 	ranges[0] = r.localNode.Identifier
 	lastIndex := 0
 	for i := 0; i <= lastPredIndex; i++ {
-		if r.predecessorList.Nodes[i].Ping() {
+		if r.predecessorList.Nodes[i] == nil {
+			log.Debug("ring:SyncData alive predecessors are not enough")
+			return nil
+		}
+		if r.ping(r.predecessorList.Nodes[i]) {
 			lastIndex++
 			ranges[lastIndex] = r.predecessorList.Nodes[i].Identifier
 		} else {
@@ -217,12 +763,7 @@ func (r *Ring) SyncData() error {
 		}
 	}
 	// if replica is 2, we only need first predecessor to current node range of data
-	localData, rootHash := r.dstore.GetRangeCircular(ranges[lastIndex], ranges[0])
-	// log.Infof("ring:SyncData db range got %d from %x to %x", len(localData), ranges[lastIndex], ranges[0])
-	if localData == nil {
-		log.Infof("ring:SyncData there is no data to replicate")
-		return nil
-	}
+	rootHash := r.dstore.RangeHash(ranges[lastIndex], ranges[0])
 	// log.Infof("ring:SyncData root hash: %x", rootHash)
 	d := NewData(nil, ranges, rootHash)
 	jsonRequest, err := SerializeData(d)
@@ -238,65 +779,274 @@ func (r *Ring) SyncData() error {
 		return nil
 	}
 
-	responseData := UnserializeData(jsonResponse)
-
-	// log.Infof("ring:SyncData received response: %v", responseData)
-	// store missing data in remote node
-	for id, record := range localData {
-		if responseData.GetRecord(id) == nil {
-			// log.Infof("ring:SyncData store on remote node: %v", record.GetJson())
-			r.successor.Store(record.GetJson())
+	// both sides are read in the same order, so successor's batches are merged with local records as they arrive
+	// missing and newer records are stored in local node, local records missing or older in successor are sent back
+	local := newRangeIterator(r.dstore, ranges[lastIndex], ranges[0])
+	err = r.successor.TransferRange(r.localNode, ranges[lastIndex], ranges[0], func(records []*Record) error {
+		for _, remote := range records {
+			for record := local.Peek(); record != nil && rangeLess(record, remote, ranges[lastIndex]); record = local.Peek() {
+				r.pushReplica(record)
+				local.Next()
+			}
+			if record := local.Peek(); record != nil && record.Namespace == remote.Namespace && record.Identifier == remote.Identifier {
+				local.Next()
+				if record.NewerThan(remote) {
+					r.pushReplica(record)
+					continue
+				}
+			}
+			r.storeReplica(remote)
 		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("ring:SyncData error in range transfer from successor: %v", err)
+		return err
 	}
-
-	// store missing data in local node
-	for id, record := range responseData.GetRecords() {
-		if localData[id] == nil {
-			// log.Infof("ring:SyncData store on local node: %v", record.GetJson())
-			r.Store(record.GetJson())
-		}
+	for record := local.Peek(); record != nil; record = local.Peek() {
+		r.pushReplica(record)
+		local.Next()
 	}
-	// log.Infof("ring:SyncData different rows: %+v", rows)
 	return nil
 }
 
+// pushReplica stores the local record in the successor
+func (r *Ring) pushReplica(record *Record) {
+	// log.Infof("ring:SyncData store on remote node: %v", record.GetJson())
+	if _, err := r.successor.StoreReplica(record.GetJson()); err != nil {
+		log.Errorf("ring:SyncData error in storing on successor: %v", err)
+	}
+}
+
+// StoreReplica stores the record pushed by another node if it's missing or newer
+// records are kept as they are, e.g. encrypted by the key of the other node
+func (r *Ring) StoreReplica(jsonData []byte) (bool, error) {
+	record := &Record{}
+	if err := json.Unmarshal(jsonData, &record); err != nil {
+		return false, ErrInvalidRecord
+	}
+	if !r.isResponsible(record.Identifier) {
+		return false, ErrNotOwner
+	}
+	if !r.isNewer(record) {
+		return false, nil
+	}
+	if err := r.verifyRecord(record); err != nil {
+		log.Warnf("ring:StoreReplica rejected record %x: %v", record.Identifier, err)
+		return false, err
+	}
+	if err := r.verifyEncryption(record); err != nil {
+		log.Warnf("ring:StoreReplica rejected record %x: %v", record.Identifier, err)
+		return false, err
+	}
+	return r.dstore.PutRecord(*record), nil
+}
+
+// storeReplica stores the record received from another node if it's missing or newer
+// the other node might be compromised, so forged records are ignored
+func (r *Ring) storeReplica(record *Record) {
+	if !r.isNewer(record) {
+		return
+	}
+	if err := r.verifyRecord(record); err != nil {
+		log.Warnf("ring:storeReplica ignored forged record %x: %v", record.Identifier, err)
+		return
+	}
+	r.dstore.PutRecord(*record)
+}
+
+// isNewer check if the record is missing in local or newer than the local one
+func (r *Ring) isNewer(record *Record) bool {
+	data := r.dstore.Get(record.Namespace, record.Identifier)
+	if data == nil {
+		return true
+	}
+	local := &Record{}
+	json.Unmarshal(data, local)
+	return record.NewerThan(local)
+}
+
 // GlobalMaintenance gets data information from predecessor to sync missing data
 func (r *Ring) GlobalMaintenance(jsonData []byte) ([]byte, error) {
-	lastPredIndex := replicas - 1
+	lastPredIndex := r.config.Replicas - 1
 	data := UnserializeData(jsonData)
 	// log.Debugf("ring:GlobalMaintenance strated %v", string(jsonData))
 
 	ranges := data.Ranges // use received ranges
 	// if replica is 2, we only need second predecessor to predecessor range of data
-	allKeysInRange, rootHash := r.dstore.GetRangeCircular(ranges[lastPredIndex], ranges[0])
-	// log.Infof("ring:GlobalMaintenance db range got %d from %x to %x", len(allKeysInRange), ranges[lastPredIndex], ranges[0])
-	if allKeysInRange == nil {
-		// log.Infof("ring:GlobalMaintenance there is no data to make hash")
-		return nil, nil
-	}
+	rootHash := r.dstore.RangeHash(ranges[lastPredIndex], ranges[0])
 	// if new root hash is the same as roothash in json data, means data is already synced
 	if helpers.Equal(rootHash, data.RootHash) {
 		return nil, nil
 	}
 
-	newData := NewData(allKeysInRange, ranges, rootHash)
+	// only the digest is returned, records are transferred by TransferRange
+	newData := NewData(nil, ranges, rootHash)
 	return SerializeData(newData)
 }
 
-func (r *Ring) Fetch(key [helpers.HashSize]byte) []byte {
-	return r.dstore.Get(key)
+// TransferRange sends records ∈ (from, to] in bounded batches
+// next batch is read after the previous one is sent, so a slow receiver doesn't grow memory
+func (r *Ring) TransferRange(from [helpers.HashSize]byte, to [helpers.HashSize]byte, after *RangeCursor, send func(records []*Record) error) error {
+	for {
+		records := r.dstore.ScanRange(from, to, after, transferBatch, transferBatchBytes)
+		if len(records) == 0 {
+			return nil
+		}
+		if err := send(records); err != nil {
+			return err
+		}
+		last := records[len(records)-1]
+		after = &RangeCursor{Namespace: last.Namespace, Key: last.Identifier}
+	}
+}
+
+func (r *Ring) Fetch(namespace string, key [helpers.HashSize]byte) ([]byte, error) {
+	if !r.isResponsible(key) {
+		return nil, ErrNotOwner
+	}
+	data := r.dstore.Get(namespace, key)
+	if data == nil || r.keyRing == nil {
+		return data, nil
+	}
+	record := &Record{}
+	json.Unmarshal(data, record)
+	if !record.IsEncrypted() || !r.keyRing.HasKey(record.KeyID) {
+		return data, nil // replicas are returned encrypted if the key is not available
+	}
+	if err := r.keyRing.Decrypt(record); err != nil {
+		return nil, err
+	}
+	return record.GetJson(), nil
+}
+
+// Scan returns records ∈ (from, to] of the namespace in ring order, at most limit records
+// next page starts after the identifier of the last returned record
+func (r *Ring) Scan(namespace string, from [helpers.HashSize]byte, to [helpers.HashSize]byte, limit int) ([]*Record, error) {
+	if !r.isResponsible(to) {
+		return nil, ErrNotOwner
+	}
+	if limit <= 0 || limit > transferBatch {
+		limit = transferBatch
+	}
+	records := r.dstore.ScanNamespace(namespace, from, to, limit, transferBatchBytes)
+	if r.keyRing == nil {
+		return records, nil
+	}
+	for _, record := range records {
+		if !record.IsEncrypted() || !r.keyRing.HasKey(record.KeyID) {
+			continue // replicas are returned encrypted if the key is not available
+		}
+		if err := r.keyRing.Decrypt(record); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
 }
 
 // Store store data
 // @todo replicate to the successor (required replications)
 // ref E.3
-func (r *Ring) Store(jsonData []byte) bool {
+func (r *Ring) Store(jsonData []byte) (bool, error) {
 	record := &Record{}
-	json.Unmarshal(jsonData, &record)
-	log.Warnf("ring:store put %s", record.Content)
+	if err := json.Unmarshal(jsonData, &record); err != nil {
+		return false, ErrInvalidRecord
+	}
+	if !r.isResponsible(record.Identifier) {
+		return false, ErrNotOwner
+	}
+	if record.KeyID != "" || len(record.Nonce) > 0 {
+		return false, ErrEncryptedRecord
+	}
+	if err := r.verifyRecord(record); err != nil {
+		log.Warnf("ring:store rejected record %x: %v", record.Identifier, err)
+		return false, err
+	}
+	if r.keyRing != nil {
+		if err := r.keyRing.Encrypt(record); err != nil {
+			return false, err
+		}
+	}
+	log.Debugf("ring:store put %x", record.Identifier)
 	stored := r.dstore.PutRecord(*record)
 	r.SyncData()
-	return stored
+	return stored, nil
+}
+
+// verifyRecord check record is signed by a trusted writer
+// unsigned records are accepted only if signatures are not required
+// without trusted writers a signature proves nothing, so signed records are accepted like unsigned ones
+// encrypted records can be verified only by the nodes which have the key, others reject them
+func (r *Ring) verifyRecord(record *Record) error {
+	if !r.requireSignedRecords && (!record.IsSigned() || len(r.trustedWriters) == 0) {
+		return nil
+	}
+	if record.IsEncrypted() {
+		if r.keyRing == nil || !r.keyRing.HasKey(record.KeyID) {
+			return ErrUnknownKey
+		}
+		plain := *record
+		if err := r.keyRing.Decrypt(&plain); err != nil {
+			return err
+		}
+		return plain.VerifySignature(r.trustedWriters)
+	}
+	return record.VerifySignature(r.trustedWriters)
+}
+
+// verifyEncryption check if the encrypted record can be decrypted by the local key
+// records of unknown keys are kept encrypted, nodes without the key ring can't open them anyway
+func (r *Ring) verifyEncryption(record *Record) error {
+	if !record.IsEncrypted() || r.keyRing == nil || !r.keyRing.HasKey(record.KeyID) {
+		return nil
+	}
+	plain := *record
+	return r.keyRing.Decrypt(&plain)
+}
+
+// Reencrypt encrypts plain records and records of old keys by the active key
+// Runs periodically, to rotate keys in background
+func (r *Ring) Reencrypt() {
+	if r.keyRing == nil {
+		return
+	}
+	if err := r.keyRing.Reload(); err != nil {
+		log.Errorf("ring:Reencrypt reloading keys failed: %v", err)
+	}
+	activeKeyID := r.keyRing.ActiveKeyID()
+	// next run continues after the last visited record
+	r.reencryptCursor = r.dstore.UpdateRecords(r.reencryptCursor, reencryptBatch, func(record *Record) bool {
+		if record.KeyID == activeKeyID {
+			return false
+		}
+		if record.IsEncrypted() && !r.keyRing.HasKey(record.KeyID) {
+			return false // replica of a record encrypted by a key we don't have
+		}
+		if err := r.keyRing.Decrypt(record); err != nil {
+			log.Errorf("ring:Reencrypt decrypting %x failed: %v", record.Identifier, err)
+			return false
+		}
+		if err := r.keyRing.Encrypt(record); err != nil {
+			log.Errorf("ring:Reencrypt encrypting %x failed: %v", record.Identifier, err)
+			return false
+		}
+		return true
+	})
+}
+
+// InvalidateLocation removes cached owner of the identifier
+func (r *Ring) InvalidateLocation(identifier [helpers.HashSize]byte) {
+	r.locationCache.Invalidate(identifier)
+}
+
+// isResponsible check if id ∈ (predecessor[replicas-1], n]
+// node keeps its own range and the replicas of its predecessors ranges
+func (r *Ring) isResponsible(identifier [helpers.HashSize]byte) bool {
+	lastPredecessor := r.predecessorList.Nodes[r.config.Replicas-1]
+	if lastPredecessor == nil { // there is not enough predecessors to know the range
+		return true
+	}
+	return helpers.BetweenR(identifier, lastPredecessor.Identifier, r.localNode.Identifier)
 }
 
 func (r *Ring) GetPredecessor(caller *RemoteNode) *RemoteNode {
@@ -9,8 +9,15 @@ import (
 // RingInterface interface for chord ring
 type RingInterface interface {
 
-	// Join joins a node to the network through remoteNode
-	Join(remoteNode *RemoteNode) error
+	// Create creates a new ring with the local node as the only member
+	Create()
+
+	// Join joins a node to the network through the first available seed
+	// returns ErrJoinFailed if all seeds fail
+	Join(seeds ...*RemoteNode) error
+
+	// IsReady returns true if the node is created or joined to a ring
+	IsReady() bool
 
 	// Verbose prints information about ring
 	Verbose()
@@ -24,13 +31,28 @@ type RingInterface interface {
 	// Stabilize checks successor if it's available, also updates successor list periodically
 	Stabilize()
 
+	// ProbeMembers checks remembered members periodically and merges the ones missing in the ring
+	ProbeMembers()
+
+	// Merge queues candidate to be placed in the ring by the maintenance,
+	// it's adopted as successor or forwarded, at most hops times
+	// ref ring unification
+	Merge(candidate *Node, hops int)
+
 	// GetLocalNode returns local node
 	GetLocalNode() *Node
 
+	// GetFailureDetector returns failure detector of the remote nodes
+	GetFailureDetector() *FailureDetector
+
 	// FindSuccessor find the closest node to the given identifier
 	// ref D
 	FindSuccessor(identifier [helpers.HashSize]byte) *RemoteNode
 
+	// FindSuccessorUncached find the closest node without using cached owners
+	// used to serve lookups of other nodes, so stale cached owners don't spread
+	FindSuccessorUncached(identifier [helpers.HashSize]byte) *RemoteNode
+
 	// Notify update predecessor
 	// is being called periodically by predecessor or new node
 	// ref E.1
@@ -41,6 +63,19 @@ type RingInterface interface {
 	// ref E.3
 	GetPredecessor(caller *RemoteNode) *RemoteNode
 
+	// GetSuccessor returns successor without side effects
+	GetSuccessor() *RemoteNode
+
+	// GetCurrentPredecessor returns predecessor without side effects, nil if it's unknown
+	GetCurrentPredecessor() *RemoteNode
+
+	// Status returns the view of the local node on the ring, without side effects
+	Status() *RingStatus
+
+	// GetSuccessorList returns successor list without side effects
+	// ref E.3
+	GetSuccessorList() *SuccessorList
+
 	// GetStabilizerData successor's (successor list and predecessor)
 	// FIXME should be cached
 	// ref E.1
@@ -52,6 +87,28 @@ type RingInterface interface {
 	SyncData() error
 	GlobalMaintenance(data []byte) ([]byte, error)
 
-	Store(data []byte) bool
-	Fetch(key [helpers.HashSize]byte) []byte
+	// TransferRange sends records ∈ (from, to] in bounded batches, starting after the cursor if it's given
+	TransferRange(from [helpers.HashSize]byte, to [helpers.HashSize]byte, after *RangeCursor, send func(records []*Record) error) error
+
+	// Store stores data if it's in the node's range, otherwise returns ErrNotOwner
+	Store(data []byte) (bool, error)
+
+	// StoreReplica stores the record pushed by another node if it's missing or newer
+	// returns ErrNotOwner if it's not in the node's range
+	StoreReplica(data []byte) (bool, error)
+
+	// Fetch returns data of the namespace if key is in the node's range, otherwise returns ErrNotOwner
+	// deleted records are returned as tombstones, so readers can compare them with other replicas
+	Fetch(namespace string, key [helpers.HashSize]byte) ([]byte, error)
+
+	// Scan returns records ∈ (from, to] of the namespace in ring order, at most limit records
+	// returns ErrNotOwner if the range is not kept by the node
+	Scan(namespace string, from [helpers.HashSize]byte, to [helpers.HashSize]byte, limit int) ([]*Record, error)
+
+	// Reencrypt encrypts plain records and records of old keys by the active key
+	Reencrypt()
+
+	// InvalidateLocation removes cached owner of the identifier
+	// should be called when cached owner responds with ErrNotOwner
+	InvalidateLocation(identifier [helpers.HashSize]byte)
 }
@@ -0,0 +1,11 @@
+package chord
+
+// RingStatus is the view of a node on the ring, used for introspection
+type RingStatus struct {
+	Node            *Node
+	Successor       *Node
+	Predecessor     *Node // nil if it's unknown
+	SuccessorList   []*Node
+	PredecessorList []*Node
+	Fingers         map[int]*Node // distinct fingers by their index
+}
@@ -0,0 +1,32 @@
+package simulator
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a virtual clock, time moves only when it's advanced
+type Clock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewClock make new virtual clock starting at the given time
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns current virtual time
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward
+// it's used as sleep of the network, so latency is reflected in round trip times
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,100 @@
+package simulator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/helpers"
+)
+
+// Violation is a broken ring invariant
+type Violation struct {
+	Node    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Node, v.Message)
+}
+
+// Check checks ring invariants of alive nodes
+// - successor is the next alive node and never the node itself in a ring of many nodes
+// - predecessor is the previous alive node
+// - successor list starts with the next alive nodes
+// - every stored key is kept by replicas nodes (or all nodes in smaller rings)
+// invariants are expected to hold only when the ring is stable and not partitioned
+func (s *Simulator) Check() []Violation {
+	nodes := s.alive()
+	sort.Slice(nodes, func(i, j int) bool {
+		return helpers.GreaterThan(nodes[j].Ring.GetLocalNode().Identifier, nodes[i].Ring.GetLocalNode().Identifier)
+	})
+	violations := []Violation{}
+	for i, node := range nodes {
+		violations = append(violations, checkNeighbors(node, nodes, i, s.Config.Replicas)...)
+	}
+	violations = append(violations, s.checkReplicas(nodes)...)
+	return violations
+}
+
+// checkNeighbors checks successor, predecessor and successor list of the node
+// nodes must be sorted by identifier
+func checkNeighbors(node *Node, nodes []*Node, index int, replicas int) []Violation {
+	violations := []Violation{}
+	local := node.Ring.GetLocalNode()
+	successor := node.Ring.GetSuccessor()
+	expectedSuccessor := nodes[(index+1)%len(nodes)].Ring.GetLocalNode()
+	if len(nodes) > 1 && successor.Identifier == local.Identifier {
+		violations = append(violations, Violation{node.Address, "successor points to the node itself"})
+	} else if successor.Identifier != expectedSuccessor.Identifier {
+		violations = append(violations, Violation{node.Address, fmt.Sprintf("successor is %s, expected %s", successor.GetFullAddress(), expectedSuccessor.GetFullAddress())})
+	}
+	if len(nodes) > 1 {
+		predecessor := node.Ring.GetCurrentPredecessor()
+		expectedPredecessor := nodes[(index-1+len(nodes))%len(nodes)].Ring.GetLocalNode()
+		if predecessor == nil {
+			violations = append(violations, Violation{node.Address, "predecessor is unknown"})
+		} else if predecessor.Identifier != expectedPredecessor.Identifier {
+			violations = append(violations, Violation{node.Address, fmt.Sprintf("predecessor is %s, expected %s", predecessor.GetFullAddress(), expectedPredecessor.GetFullAddress())})
+		}
+	}
+	successors := node.Ring.GetSuccessorList().GetNodes()
+	expected := replicas
+	if expected > len(nodes)-1 {
+		expected = len(nodes) - 1
+	}
+	for i := 0; i < expected; i++ {
+		expectedNode := nodes[(index+1+i)%len(nodes)].Ring.GetLocalNode()
+		if i >= len(successors) {
+			violations = append(violations, Violation{node.Address, fmt.Sprintf("successor list has %d nodes, expected at least %d", len(successors), expected)})
+			break
+		}
+		if successors[i].Identifier != expectedNode.Identifier {
+			violations = append(violations, Violation{node.Address, fmt.Sprintf("successor list[%d] is %s, expected %s", i, successors[i].GetFullAddress(), expectedNode.GetFullAddress())})
+		}
+	}
+	return violations
+}
+
+// checkReplicas checks if stored keys are kept by enough nodes
+// a node keeps a replica if it's responsible for the key and has it
+func (s *Simulator) checkReplicas(nodes []*Node) []Violation {
+	violations := []Violation{}
+	expected := s.Config.Replicas
+	if expected > len(nodes) {
+		expected = len(nodes)
+	}
+	for _, key := range s.keys {
+		identifier := chord.RecordIdentifier("", key)
+		count := 0
+		for _, node := range nodes {
+			if data, err := node.Ring.Fetch("", identifier); err == nil && data != nil {
+				count++
+			}
+		}
+		if count < expected {
+			violations = append(violations, Violation{key, fmt.Sprintf("key has %d replicas, expected %d", count, expected)})
+		}
+	}
+	return violations
+}
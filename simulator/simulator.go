@@ -0,0 +1,271 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/mbrostami/chord"
+	"github.com/mbrostami/chord/memory"
+)
+
+// default maintenance intervals in steps, same as the intervals of cmd
+const (
+	defaultTick          time.Duration = time.Second
+	defaultSyncDataEvery int           = 10
+	defaultProbeEvery    int           = 5
+)
+
+// Node is a simulated node
+type Node struct {
+	Address string
+	Ring    chord.RingInterface
+	Alive   bool
+}
+
+// Simulator drives rings connected by an in-memory network with a virtual clock
+// maintenance runs in a fixed order of nodes, so a run is reproducible by its seed
+type Simulator struct {
+	Clock   *Clock
+	Network *memory.Network
+	// Config of new nodes, clock is replaced by the virtual clock
+	Config chord.Config
+	// Tick is the virtual time of each step
+	Tick time.Duration
+	// SyncDataEvery is the number of steps between data synchronizations
+	SyncDataEvery int
+	// ProbeEvery is the number of steps between probes of remembered members
+	ProbeEvery int
+
+	nodes  []*Node
+	keys   []string // stored keys to check replicas
+	step   int
+	random *rand.Rand
+}
+
+// New make new simulator
+func New(seed int64) *Simulator {
+	clock := NewClock(time.Unix(0, 0))
+	network := memory.NewNetwork(seed)
+	network.SetSleep(clock.Advance)
+	return &Simulator{
+		Clock:         clock,
+		Network:       network,
+		Config:        chord.DefaultConfig(),
+		Tick:          defaultTick,
+		SyncDataEvery: defaultSyncDataEvery,
+		ProbeEvery:    defaultProbeEvery,
+		random:        rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Nodes returns all simulated nodes including the failed ones
+func (s *Simulator) Nodes() []*Node {
+	return s.nodes
+}
+
+// CurrentStep returns the number of steps run so far
+func (s *Simulator) CurrentStep() int {
+	return s.step
+}
+
+// AddNode starts a new node, first node creates the ring and others join through a random alive node
+func (s *Simulator) AddNode(ip string, port uint) (*Node, error) {
+	address := chord.NewNode(ip, port).GetFullAddress()
+	if s.node(address) != nil {
+		return nil, fmt.Errorf("node %s already exists", address)
+	}
+	os.Remove(chord.DStorePath(s.Config.DataDir, address)) // start with empty storage
+	localNode := chord.NewNode(ip, port)
+	sender := s.Network.Sender(address)
+	config := s.Config
+	config.Clock = s.Clock
+	ring, err := chord.NewRing(localNode, sender, config)
+	if err != nil {
+		return nil, err
+	}
+	s.Network.Register(ring)
+	bootstrap := s.randomAlive()
+	if bootstrap == nil {
+		ring.Create()
+	} else if err := ring.Join(chord.NewRemoteNode(bootstrap.Ring.GetLocalNode(), sender)); err != nil {
+		s.Network.Unregister(address)
+		return nil, err
+	}
+	node := &Node{Address: address, Ring: ring, Alive: true}
+	s.nodes = append(s.nodes, node)
+	return node, nil
+}
+
+// Fail crashes the node, it doesn't respond anymore
+func (s *Simulator) Fail(address string) error {
+	node := s.node(address)
+	if node == nil || !node.Alive {
+		return fmt.Errorf("node %s is not alive", address)
+	}
+	node.Alive = false
+	s.Network.Unregister(address)
+	return nil
+}
+
+// FailRandom crashes a random alive node
+func (s *Simulator) FailRandom() (*Node, error) {
+	node := s.randomAlive()
+	if node == nil {
+		return nil, errors.New("there is no alive node")
+	}
+	return node, s.Fail(node.Address)
+}
+
+// Partition splits the network into the groups of addresses
+func (s *Simulator) Partition(groups ...[]string) {
+	s.Network.Partition(groups...)
+}
+
+// PartitionHalves splits alive nodes into two partitions by their order of joining
+func (s *Simulator) PartitionHalves() ([]string, []string) {
+	nodes := s.alive()
+	first := []string{}
+	second := []string{}
+	for i, node := range nodes {
+		if i < len(nodes)/2 {
+			first = append(first, node.Address)
+		} else {
+			second = append(second, node.Address)
+		}
+	}
+	s.Partition(first, second)
+	return first, second
+}
+
+// Heal removes partitions
+func (s *Simulator) Heal() {
+	s.Network.Heal()
+}
+
+// Store stores the key through a random alive node
+// stored keys are checked to have enough replicas
+func (s *Simulator) Store(key string, value []byte) error {
+	node := s.randomAlive()
+	if node == nil {
+		return errors.New("there is no alive node")
+	}
+	record := &chord.Record{
+		CreationTime: s.Clock.Now(),
+		Content:      value,
+		Identifier:   chord.RecordIdentifier("", key),
+	}
+	owner := node.Ring.FindSuccessor(record.Identifier)
+	if owner == nil {
+		return fmt.Errorf("owner of %s is not found", key)
+	}
+	_, err := owner.Store(record.GetJson())
+	if err == chord.ErrNotOwner {
+		// cached owner is changed, retry with a fresh lookup
+		node.Ring.InvalidateLocation(record.Identifier)
+		if owner = node.Ring.FindSuccessor(record.Identifier); owner == nil {
+			return fmt.Errorf("owner of %s is not found", key)
+		}
+		_, err = owner.Store(record.GetJson())
+	}
+	if err != nil {
+		return err
+	}
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+// Step advances the clock by one tick and runs maintenance of all alive nodes
+func (s *Simulator) Step() {
+	s.step++
+	s.Clock.Advance(s.Tick)
+	for _, node := range s.alive() {
+		node.Ring.Stabilize()
+		node.Ring.CheckPredecessor()
+		node.Ring.FixFingers()
+	}
+	if s.ProbeEvery > 0 && s.step%s.ProbeEvery == 0 {
+		for _, node := range s.alive() {
+			node.Ring.ProbeMembers()
+		}
+	}
+	if s.SyncDataEvery > 0 && s.step%s.SyncDataEvery == 0 {
+		for _, node := range s.alive() {
+			node.Ring.SyncData()
+		}
+	}
+}
+
+// Event is a scripted action which runs before the given step
+type Event struct {
+	Step   int
+	Name   string
+	Action func(s *Simulator) error
+}
+
+// StepResult invariant violations after a step
+type StepResult struct {
+	Step       int
+	Violations []Violation
+}
+
+// Run runs the steps and the scripted events, invariants are checked after each step
+// returns an error if an event fails
+func (s *Simulator) Run(steps int, script []Event) ([]StepResult, error) {
+	results := make([]StepResult, 0, steps)
+	for i := 0; i < steps; i++ {
+		next := s.step + 1
+		for _, event := range script {
+			if event.Step != next {
+				continue
+			}
+			if err := event.Action(s); err != nil {
+				return results, fmt.Errorf("step %d: %s: %v", next, event.Name, err)
+			}
+		}
+		s.Step()
+		results = append(results, StepResult{Step: s.step, Violations: s.Check()})
+	}
+	return results, nil
+}
+
+// RunUntilStable runs until no invariant is violated or maxSteps is reached
+// returns the number of steps and the last violations
+func (s *Simulator) RunUntilStable(maxSteps int) (int, []Violation) {
+	violations := s.Check()
+	steps := 0
+	for ; steps < maxSteps && len(violations) > 0; steps++ {
+		s.Step()
+		violations = s.Check()
+	}
+	return steps, violations
+}
+
+func (s *Simulator) node(address string) *Node {
+	for _, node := range s.nodes {
+		if node.Address == address {
+			return node
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) alive() []*Node {
+	nodes := []*Node{}
+	for _, node := range s.nodes {
+		if node.Alive {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (s *Simulator) randomAlive() *Node {
+	nodes := s.alive()
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[s.random.Intn(len(nodes))]
+}
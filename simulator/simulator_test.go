@@ -0,0 +1,126 @@
+package simulator_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mbrostami/chord/simulator"
+	log "github.com/sirupsen/logrus"
+)
+
+// stepBudget is the maximum steps for the ring to be stable after each phase
+const stepBudget int = 100
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.FatalLevel)
+	os.Exit(m.Run())
+}
+
+// newSimulator makes a stable ring of the given size with stored keys
+func newSimulator(t *testing.T, seed int64, nodes int, keys int) *simulator.Simulator {
+	dataDir, err := ioutil.TempDir("", "chord-simulator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dataDir) })
+	sim := simulator.New(seed)
+	sim.Config.DataDir = dataDir
+	for i := 0; i < nodes; i++ {
+		if _, err := sim.AddNode(fmt.Sprintf("10.2.%d.%d", i/250, i%250+1), 10001); err != nil {
+			t.Fatalf("node %d failed to join: %v", i, err)
+		}
+	}
+	assertStable(t, sim, "join")
+	for i := 0; i < keys; i++ {
+		if err := sim.Store(fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i))); err != nil {
+			t.Fatalf("storing key-%d failed: %v", i, err)
+		}
+	}
+	assertStable(t, sim, "store")
+	return sim
+}
+
+func assertStable(t *testing.T, sim *simulator.Simulator, phase string) {
+	t.Helper()
+	steps, violations := sim.RunUntilStable(stepBudget)
+	if len(violations) > 0 {
+		for _, violation := range violations {
+			t.Errorf("%s: %s", phase, violation)
+		}
+		t.Fatalf("%s: %d violations after %d steps", phase, len(violations), steps)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		newSimulator(t, seed, 30, 20)
+	}
+}
+
+func TestCrash(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		sim := newSimulator(t, seed, 30, 20)
+		for i := 0; i < 3; i++ {
+			if _, err := sim.FailRandom(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assertStable(t, sim, fmt.Sprintf("crash with seed %d", seed))
+	}
+}
+
+func TestPartitionThenHeal(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		sim := newSimulator(t, seed, 30, 20)
+		sim.PartitionHalves()
+		sim.Run(30, nil)
+		sim.Heal()
+		assertStable(t, sim, fmt.Sprintf("heal with seed %d", seed))
+	}
+}
+
+// TestSuccessorIsNotItselfAfterCrash predecessor of a crashed node must pick the next successor from its list
+// it used to set its successor to itself, so the ring was broken until another node notified it
+func TestSuccessorIsNotItselfAfterCrash(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		sim := newSimulator(t, seed, 10, 0)
+		for crash := 0; crash < 3; crash++ {
+			if _, err := sim.FailRandom(); err != nil {
+				t.Fatal(err)
+			}
+			for step := 0; step < 30; step++ {
+				sim.Step()
+				for _, node := range sim.Nodes() {
+					if !node.Alive {
+						continue
+					}
+					if successor := node.Ring.GetSuccessor(); successor.Identifier == node.Ring.GetLocalNode().Identifier {
+						t.Fatalf("seed %d: %s set its successor to itself after crash %d, step %d", seed, node.Address, crash+1, step+1)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestSmallRingCrash predecessor is the second successor in a ring of 3 nodes, it must be kept in the successor list
+// it used to be dropped, so the node whose successor crashed had no other successor
+func TestSmallRingCrash(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		sim := newSimulator(t, seed, 3, 10)
+		if _, err := sim.FailRandom(); err != nil {
+			t.Fatal(err)
+		}
+		for step := 0; step < 30; step++ {
+			sim.Step()
+			for _, node := range sim.Nodes() {
+				if node.Alive && node.Ring.GetSuccessor().Identifier == node.Ring.GetLocalNode().Identifier {
+					t.Fatalf("seed %d: %s set its successor to itself, step %d", seed, node.Address, step+1)
+				}
+			}
+		}
+		assertStable(t, sim, fmt.Sprintf("crash in small ring with seed %d", seed))
+	}
+}
@@ -7,15 +7,20 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// errSuccessorNotSuspected successor is not responding, but it's not suspected as failed yet
+var errSuccessorNotSuspected = errors.New("successor is not responding")
+
 type Stabilizer struct {
 	successorList   *SuccessorList
 	predecessorList *PredecessorList
+	failureDetector *FailureDetector
 }
 
-func NewStabilizer(successorList *SuccessorList, predecessorList *PredecessorList) *Stabilizer {
+func NewStabilizer(successorList *SuccessorList, predecessorList *PredecessorList, failureDetector *FailureDetector) *Stabilizer {
 	return &Stabilizer{
 		successorList:   successorList,
 		predecessorList: predecessorList,
+		failureDetector: failureDetector,
 	}
 }
 
@@ -23,7 +28,10 @@ func NewStabilizer(successorList *SuccessorList, predecessorList *PredecessorLis
 // Runs periodically
 // ref E.1 - E.3
 func (s *Stabilizer) StartSuccessorList(successor *RemoteNode, localNode *Node) (*RemoteNode, *SuccessorList, error) {
-	successor, remotePredecessor, successorList := s.getSuccessorStablizerData(successor, localNode)
+	successor, remotePredecessor, successorList, err := s.getSuccessorStablizerData(successor, localNode)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// if all successors failed, then skip stabilizer to run next time
 	if remotePredecessor.Node == nil || localNode == nil {
@@ -48,21 +56,32 @@ func (s *Stabilizer) StartPredecessorList(predecessor *RemoteNode, localNode *No
 }
 
 // getSuccessorStablizerData get stabilizer data from successor
-// if successor is not available, replace it with the next available successor
-func (s *Stabilizer) getSuccessorStablizerData(successor *RemoteNode, localNode *Node) (*RemoteNode, *RemoteNode, *SuccessorList) {
+// if successor is suspected as failed, replace it with the next available successor
+func (s *Stabilizer) getSuccessorStablizerData(successor *RemoteNode, localNode *Node) (*RemoteNode, *RemoteNode, *SuccessorList, error) {
 	remotePredecessor, successorList, err := successor.GetStablizerData(localNode)
 	if err != nil {
+		// successor which never responded is tracked since its first failure, so it can be suspected
+		s.failureDetector.Track(successor.Identifier)
+	}
+	if err == nil {
+		s.failureDetector.Heartbeat(successor.Identifier)
+	} else if !s.failureDetector.Suspected(successor.Identifier) {
+		// transient failure, skip stabilizer to run next time
+		log.Debugf("successor %x is not responding, phi: %f", successor.Identifier, s.failureDetector.Phi(successor.Identifier))
+		return nil, nil, nil, errSuccessorNotSuspected
+	} else {
 		// replace next available successor from successorList
 		for i := 1; i < len(s.successorList.Nodes); i++ {
 			remotNode := s.successorList.Nodes[i]
 			remotePredecessor, successorList, err = remotNode.GetStablizerData(localNode)
 			if err == nil {
+				s.failureDetector.Heartbeat(remotNode.Identifier)
 				successor = remotNode
 				break
 			}
 		}
 	}
-	return successor, remotePredecessor, successorList
+	return successor, remotePredecessor, successorList, nil
 }
 
 // getPredecessorList get predecessor list from predecessor
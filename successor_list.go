@@ -6,7 +6,7 @@ import (
 	"github.com/mbrostami/chord/helpers"
 )
 
-// RSIZE is the number of records in successor list
+// RSIZE is the default number of records in successor list
 // could be (log n) refer to Theorem IV.5
 // Increasing r makes the system more robust
 // ref E.3 - Theorem IV.5
@@ -21,36 +21,38 @@ type SuccessorList struct {
 
 // NewSuccessorList make new successor list
 func NewSuccessorList() *SuccessorList {
+	return newSuccessorList(RSIZE)
+}
+
+func newSuccessorList(r int) *SuccessorList {
 	return &SuccessorList{
 		Nodes: make(map[int]*RemoteNode),
-		r:     RSIZE,
+		r:     r,
 	}
 }
 
-func (sl *SuccessorList) ClosestPrecedingNode(identifier [helpers.HashSize]byte, localNode *Node, fingerTableClosest *RemoteNode) *RemoteNode {
+// ClosestPrecedingNodes returns successors ∈ (n, id)
+// ordered from the closest to the farthest preceding node of the identifier
+// ref E.3 - modified version of closestPrecedingNode will also check the successor list
+func (sl *SuccessorList) ClosestPrecedingNodes(identifier [helpers.HashSize]byte, localNode *Node) []*RemoteNode {
 	sl.mutex.RLock()
 	defer sl.mutex.RUnlock()
-	// ref E.3 - modified version of closestPrecedingNode will also check the successor list
-	// to find closest node to the identifier
-	for i := 0; i < len(sl.Nodes); i++ {
-		// successorList[i] ∈ (fingerTable[k], id)
-		// if successor item is closer to identifier than the found fingertable item, then return successor item
-		if fingerTableClosest != nil {
-			if helpers.BetweenR(sl.Nodes[i].Identifier, fingerTableClosest.Identifier, identifier) {
-				return sl.Nodes[i]
-			}
-		} else {
-			// successorList[i] ∈ (n, id)
-			if helpers.BetweenR(sl.Nodes[i].Identifier, localNode.Identifier, identifier) {
-				return sl.Nodes[i]
-			}
+	nodes := []*RemoteNode{}
+	// successor list is sorted, so the last items are closer to the identifier
+	for i := len(sl.Nodes) - 1; i >= 0; i-- {
+		if sl.Nodes[i] == nil {
+			continue
+		}
+		// successorList[i] ∈ (n, id)
+		if helpers.Between(sl.Nodes[i].Identifier, localNode.Identifier, identifier) {
+			nodes = append(nodes, sl.Nodes[i])
 		}
 	}
-	return nil
+	return nodes
 }
 
 // UpdateSuccessorList updates successor list - ref E.3
-func (sl *SuccessorList) UpdateSuccessorList(successor *RemoteNode, predecessor *RemoteNode, localNode *Node, successorList *SuccessorList) {
+func (sl *SuccessorList) UpdateSuccessorList(successor *RemoteNode, localNode *Node, successorList *SuccessorList) {
 	if successorList == nil || successor == nil {
 		return
 	}
@@ -64,20 +66,38 @@ func (sl *SuccessorList) UpdateSuccessorList(successor *RemoteNode, predecessor
 			break
 		}
 		chorNode := successorList.Nodes[i]
-		// ignore same nodes
-		if chorNode.Identifier == localNode.Identifier {
-			continue
+		if chorNode == nil {
+			break
 		}
 		// in small networks where the number of nodes are smaller than sl.r number
-		// it's possible to have current node predecessor in successor's successorlist
-		// to prevent this loop, we should check successorlist and be sure to remove those
-		// in order to prevent a loop (including failed nodes)
-		if predecessor != nil {
-			if chorNode.Identifier == predecessor.Identifier {
-				break // skip next records including current one
-			}
+		// successor's successorlist wraps around the ring to the current node
+		// next records are repeated, so the list ends here
+		// predecessor is kept, it's the next successor if the ones before it fail
+		if chorNode.Identifier == localNode.Identifier {
+			break
 		}
 		sl.Nodes[index] = chorNode
 		index++
 	}
 }
+
+// GetNodes returns a sorted copy of successors
+func (sl *SuccessorList) GetNodes() []*RemoteNode {
+	sl.mutex.RLock()
+	defer sl.mutex.RUnlock()
+	nodes := []*RemoteNode{}
+	for i := 0; sl.Nodes[i] != nil; i++ {
+		nodes = append(nodes, sl.Nodes[i])
+	}
+	return nodes
+}
+
+// Contains check if the node is one of the successors
+func (sl *SuccessorList) Contains(identifier [helpers.HashSize]byte) bool {
+	for _, node := range sl.GetNodes() {
+		if node.Identifier == identifier {
+			return true
+		}
+	}
+	return false
+}